@@ -0,0 +1,32 @@
+package main
+
+import (
+	"api-fetch/internal/api_fetch/alert"
+	"api-fetch/internal/api_fetch/api"
+	"api-fetch/internal/api_fetch/processor"
+	"api-fetch/pkg/mongodb"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config 服务配置；mongo 段沿用 pkg/mongodb 的定义，其余为抓取服务自身的配置
+type Config struct {
+	Mongo   mongodb.MongoConfig     `yaml:"mongo"`
+	Alert   alert.Config            `yaml:"alert"`
+	Anomaly processor.AnomalyConfig `yaml:"anomaly"`
+	Fetch   processor.FetchConfig   `yaml:"fetch"`
+	Admin   api.AdminConfig         `yaml:"admin"`
+}
+
+func loadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// component 由生命周期管理器托管的组件
+// run 应阻塞直到 ctx 被取消或组件自身出错；ctx 取消后正常退出时返回 nil
+type component struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+// lifecycle 统一管理调度器与 HTTP 服务的启动和退出
+type lifecycle struct {
+	log        *zap.Logger
+	components []component
+}
+
+func newLifecycle(log *zap.Logger) *lifecycle {
+	return &lifecycle{log: log}
+}
+
+// add 注册一个组件
+func (l *lifecycle) add(name string, run func(ctx context.Context) error) {
+	l.components = append(l.components, component{name: name, run: run})
+}
+
+// Run 并发启动所有组件，收到 SIGINT/SIGTERM 或任一组件出错时取消共享 ctx，
+// 等待全部组件退出后返回第一个错误
+func (l *lifecycle) Run(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	g, gctx := errgroup.WithContext(ctx)
+	for _, c := range l.components {
+		c := c
+		g.Go(func() (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					err = fmt.Errorf("%s panicked: %v", c.name, r)
+				}
+			}()
+
+			l.log.Info("Component starting", zap.String("component", c.name))
+			if err := c.run(gctx); err != nil {
+				l.log.Error("Component failed", zap.String("component", c.name), zap.Error(err))
+				return fmt.Errorf("%s: %w", c.name, err)
+			}
+			l.log.Info("Component stopped", zap.String("component", c.name))
+			return nil
+		})
+	}
+
+	// 收到信号后打印一次日志，方便排查退出原因
+	go func() {
+		<-gctx.Done()
+		if ctx.Err() != nil {
+			l.log.Info("Shutdown signal received, stopping components...")
+		}
+	}()
+
+	return g.Wait()
+}
+
+// serveHTTP 将 http.Server 包装为组件：ctx 取消后在 timeout 内优雅关闭
+func serveHTTP(log *zap.Logger, srv *http.Server, timeout time.Duration) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		errCh := make(chan error, 1)
+		go func() {
+			log.Info("API Fetch Service is running", zap.String("address", srv.Addr))
+			errCh <- srv.ListenAndServe()
+		}()
+
+		select {
+		case err := <-errCh:
+			// 未经 Shutdown 就退出，视为启动或运行失败
+			if errors.Is(err, http.ErrServerClosed) {
+				return nil
+			}
+			return err
+		case <-ctx.Done():
+		}
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			return fmt.Errorf("graceful shutdown: %w", err)
+		}
+		if err := <-errCh; err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	}
+}
@@ -1,14 +1,15 @@
 package main
 
 import (
+	"api-fetch/internal/api_fetch/alert"
 	"api-fetch/internal/api_fetch/api"
 	"api-fetch/internal/api_fetch/helper"
 	"api-fetch/internal/api_fetch/scheduler"
 	"api-fetch/internal/middleware/logger"
-	"api-fetch/pkg/mongodb"
 	"context"
 	"go.uber.org/zap"
 	"net/http"
+	"os"
 	"time"
 )
 
@@ -33,7 +34,7 @@ func main() {
 		panic(err) // 或者日志+退出
 	}
 
-	cfg, err := mongodb.LoadConfig("config/1-config.yaml")
+	cfg, err := loadConfig("config/1-config.yaml")
 	if err != nil {
 		panic(err)
 	}
@@ -47,26 +48,42 @@ func main() {
 		cfg.Mongo.AuthSource,
 	)
 
+	alerts, err := alert.NewManager(log, cfg.Alert)
+	if err != nil {
+		panic(err)
+	}
+
 	worker := scheduler.NewScheduler(
 		log,
 		stores,
 		&http.Client{Timeout: 10 * time.Second},
+		alerts,
+		cfg.Anomaly,
+		cfg.Fetch,
 	)
-	worker.Run(ctx)
-
-	//dp := processor.NewDataProcessor(log, stores)
-	//dp.Run(ctx, []processor.DataProcessorConfig{
-	//	{
-	//		Source:   "澎湃",
-	//		Category: "general",
-	//		InfoType: "daily",
-	//		Enabled:  true,
-	//	},
-	//})
 
-	srv := &api.Server{Stores: stores}
+	if len(cfg.Admin.Tokens) == 0 {
+		log.Warn("No admin tokens configured, mutating admin endpoints will reject all requests")
+	}
+	srv := &api.Server{Stores: stores, Log: log, Scheduler: worker, Admin: cfg.Admin}
 	r := srv.Router()
 	_ = r.SetTrustedProxies(nil)
-	log.Info("API Fetch Service is running", zap.String("address", ":8080"))
-	_ = r.Run(":8080")
+	httpServer := &http.Server{
+		Addr:    ":8080",
+		Handler: r,
+	}
+
+	// 调度器与 HTTP 服务并行运行，任一退出（信号或出错）都会停止另一个
+	lc := newLifecycle(log)
+	lc.add("scheduler", worker.Run)
+	lc.add("http", serveHTTP(log, httpServer, 15*time.Second))
+
+	err = lc.Run(ctx)
+	alerts.Wait()
+	if err != nil {
+		log.Error("API Fetch Service exited with error", zap.Error(err))
+		_ = log.Sync()
+		os.Exit(1)
+	}
+	log.Info("API Fetch Service stopped")
 }
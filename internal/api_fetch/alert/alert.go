@@ -0,0 +1,206 @@
+// Package alert 抓取与后处理异常的告警通知：按配置发送到 Webhook、IM 机器人或邮件，
+// 同一告警在冷却期内只发送一次
+package alert
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 告警类型
+const (
+	EventRetryExhausted      = "retry_exhausted"      // 异步重试用尽
+	EventConsecutiveFailures = "consecutive_failures" // 连续多次运行失败
+	EventRequiredMismatch    = "required_mismatch"    // Required 校验不通过
+	EventProcessorFailure    = "processor_failure"    // 后处理失败
+	EventSchemaDrift         = "schema_drift"         // 上游响应结构变化
+	EventItemAnomaly         = "item_anomaly"         // 条目数为零或明显低于基线
+	EventCircuitOpen         = "circuit_open"         // 上游持续故障，暂停抓取
+)
+
+// 默认参数
+const (
+	defaultCooldown            = 30 * time.Minute
+	defaultConsecutiveFailures = 3
+	sendTimeout                = 10 * time.Second
+)
+
+// Config 告警配置（config.yaml 中的 alert 段）
+type Config struct {
+	Cooldown            time.Duration   `yaml:"cooldown"`             // 同一告警的最短发送间隔，默认 30m
+	ConsecutiveFailures int             `yaml:"consecutive_failures"` // 连续失败多少次运行后告警，默认 3
+	Channels            []ChannelConfig `yaml:"channels"`
+}
+
+// ChannelConfig 单个通知渠道
+type ChannelConfig struct {
+	Name    string            `yaml:"name"`
+	Type    string            `yaml:"type"` // webhook | slack | feishu | dingtalk | smtp
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"` // 仅 webhook
+	Secret  string            `yaml:"secret"`  // 飞书 / 钉钉机器人的加签密钥
+	Events  []string          `yaml:"events"`  // 订阅的告警类型，为空表示全部
+	SMTP    *SMTPConfig       `yaml:"smtp"`
+}
+
+// SMTPConfig 邮件渠道配置
+type SMTPConfig struct {
+	Host     string   `yaml:"host"`
+	Port     int      `yaml:"port"`
+	Username string   `yaml:"username"`
+	Password string   `yaml:"password"`
+	From     string   `yaml:"from"`
+	To       []string `yaml:"to"`
+}
+
+// Alert 一条告警
+type Alert struct {
+	Event      string            `json:"event"`
+	Source     string            `json:"source,omitempty"`
+	Category   string            `json:"category,omitempty"`
+	InfoType   string            `json:"info_type,omitempty"`
+	APIID      string            `json:"api_id,omitempty"`
+	Title      string            `json:"title"`
+	Message    string            `json:"message"`
+	Fields     map[string]string `json:"fields,omitempty"`
+	Suppressed int               `json:"suppressed,omitempty"` // 上次发送后冷却期内被抑制的次数
+	Time       time.Time         `json:"time"`
+}
+
+// dedupKey 同一类型、同一数据源的告警视为重复
+func (a Alert) dedupKey() string {
+	return strings.Join([]string{a.Event, a.APIID, a.Source, a.Category, a.InfoType}, "|")
+}
+
+// Text 纯文本格式，用于 IM 与邮件
+func (a Alert) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s] %s\n%s", a.Event, a.Title, a.Message)
+	if a.Source != "" {
+		fmt.Fprintf(&b, "\nsource: %s / %s / %s", a.Source, a.Category, a.InfoType)
+	}
+	keys := make([]string, 0, len(a.Fields))
+	for k := range a.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, "\n%s: %s", k, a.Fields[k])
+	}
+	if a.Suppressed > 0 {
+		fmt.Fprintf(&b, "\n(%d similar alerts suppressed since last notification)", a.Suppressed)
+	}
+	fmt.Fprintf(&b, "\ntime: %s", a.Time.Format(time.RFC3339))
+	return b.String()
+}
+
+// dedupState 某个告警最近一次发送的状态
+type dedupState struct {
+	lastSent   time.Time
+	suppressed int
+}
+
+// Manager 告警管理器；nil 时所有方法为空操作
+type Manager struct {
+	log      *zap.Logger
+	cooldown time.Duration
+	failures int
+	channels []channel
+
+	mu    sync.Mutex
+	state map[string]*dedupState
+	wg    sync.WaitGroup
+}
+
+// NewManager 根据配置创建告警管理器，渠道配置有误时返回错误
+func NewManager(log *zap.Logger, cfg Config) (*Manager, error) {
+	m := &Manager{
+		log:      log,
+		cooldown: cfg.Cooldown,
+		failures: cfg.ConsecutiveFailures,
+		state:    make(map[string]*dedupState),
+	}
+	if m.cooldown <= 0 {
+		m.cooldown = defaultCooldown
+	}
+	if m.failures <= 0 {
+		m.failures = defaultConsecutiveFailures
+	}
+	for i, cc := range cfg.Channels {
+		ch, err := newChannel(cc)
+		if err != nil {
+			return nil, fmt.Errorf("alert channel %d (%s): %w", i, cc.Name, err)
+		}
+		m.channels = append(m.channels, ch)
+	}
+	return m, nil
+}
+
+// ConsecutiveFailures 连续失败多少次运行后告警
+func (m *Manager) ConsecutiveFailures() int {
+	if m == nil {
+		return defaultConsecutiveFailures
+	}
+	return m.failures
+}
+
+// Notify 异步发送告警；冷却期内的重复告警只计数，下次发送时附带被抑制的次数
+func (m *Manager) Notify(a Alert) {
+	if m == nil || len(m.channels) == 0 {
+		return
+	}
+	if a.Time.IsZero() {
+		a.Time = time.Now()
+	}
+
+	key := a.dedupKey()
+	m.mu.Lock()
+	st, ok := m.state[key]
+	if !ok {
+		st = &dedupState{}
+		m.state[key] = st
+	}
+	if !st.lastSent.IsZero() && a.Time.Sub(st.lastSent) < m.cooldown {
+		st.suppressed++
+		m.mu.Unlock()
+		return
+	}
+	a.Suppressed = st.suppressed
+	st.lastSent = a.Time
+	st.suppressed = 0
+	m.mu.Unlock()
+
+	for _, ch := range m.channels {
+		if !ch.subscribed(a.Event) {
+			continue
+		}
+		m.wg.Add(1)
+		go func(ch channel) {
+			defer m.wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+			defer cancel()
+			if err := ch.send(ctx, a); err != nil {
+				m.log.Warn("Failed to send alert",
+					zap.String("channel", ch.name()),
+					zap.String("event", a.Event),
+					zap.String("source", a.Source),
+					zap.Error(err),
+				)
+			}
+		}(ch)
+	}
+}
+
+// Wait 等待发送中的告警完成，服务退出前调用
+func (m *Manager) Wait() {
+	if m == nil {
+		return
+	}
+	m.wg.Wait()
+}
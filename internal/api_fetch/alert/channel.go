@@ -0,0 +1,217 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 渠道类型
+const (
+	ChannelWebhook  = "webhook"
+	ChannelSlack    = "slack"
+	ChannelFeishu   = "feishu"
+	ChannelDingTalk = "dingtalk"
+	ChannelSMTP     = "smtp"
+)
+
+// channel 通知渠道
+type channel interface {
+	name() string
+	subscribed(event string) bool
+	send(ctx context.Context, a Alert) error
+}
+
+// baseChannel 渠道的公共部分：名称与订阅的告警类型
+type baseChannel struct {
+	label  string
+	events map[string]struct{}
+}
+
+func (b baseChannel) name() string { return b.label }
+
+func (b baseChannel) subscribed(event string) bool {
+	if len(b.events) == 0 {
+		return true
+	}
+	_, ok := b.events[event]
+	return ok
+}
+
+// newChannel 按类型创建渠道
+func newChannel(cfg ChannelConfig) (channel, error) {
+	base := baseChannel{label: cfg.Name}
+	if base.label == "" {
+		base.label = cfg.Type
+	}
+	if len(cfg.Events) > 0 {
+		base.events = make(map[string]struct{}, len(cfg.Events))
+		for _, e := range cfg.Events {
+			base.events[e] = struct{}{}
+		}
+	}
+
+	switch strings.ToLower(cfg.Type) {
+	case ChannelWebhook, ChannelSlack, ChannelFeishu, ChannelDingTalk:
+		if _, err := url.ParseRequestURI(cfg.URL); err != nil {
+			return nil, fmt.Errorf("invalid url: %w", err)
+		}
+		return &httpChannel{
+			baseChannel: base,
+			kind:        strings.ToLower(cfg.Type),
+			url:         cfg.URL,
+			headers:     cfg.Headers,
+			secret:      cfg.Secret,
+			client:      &http.Client{Timeout: sendTimeout},
+		}, nil
+	case ChannelSMTP:
+		if cfg.SMTP == nil || cfg.SMTP.Host == "" || cfg.SMTP.From == "" || len(cfg.SMTP.To) == 0 {
+			return nil, fmt.Errorf("smtp host, from and to are required")
+		}
+		return &smtpChannel{baseChannel: base, cfg: *cfg.SMTP}, nil
+	default:
+		return nil, fmt.Errorf("unsupported channel type %q", cfg.Type)
+	}
+}
+
+// httpChannel 通过 HTTP POST 发送：通用 JSON Webhook 或 Slack / 飞书 / 钉钉机器人
+type httpChannel struct {
+	baseChannel
+	kind    string
+	url     string
+	headers map[string]string
+	secret  string
+	client  *http.Client
+}
+
+func (c *httpChannel) send(ctx context.Context, a Alert) error {
+	target := c.url
+	var payload any
+	switch c.kind {
+	case ChannelSlack:
+		payload = map[string]any{"text": a.Text()}
+	case ChannelFeishu:
+		msg := map[string]any{
+			"msg_type": "text",
+			"content":  map[string]string{"text": a.Text()},
+		}
+		if c.secret != "" {
+			ts := strconv.FormatInt(time.Now().Unix(), 10)
+			msg["timestamp"] = ts
+			msg["sign"] = feishuSign(ts, c.secret)
+		}
+		payload = msg
+	case ChannelDingTalk:
+		payload = map[string]any{
+			"msgtype": "text",
+			"text":    map[string]string{"content": a.Text()},
+		}
+		if c.secret != "" {
+			signed, err := dingTalkURL(c.url, c.secret, time.Now())
+			if err != nil {
+				return err
+			}
+			target = signed
+		}
+	default:
+		payload = a
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, respBody)
+	}
+	return nil
+}
+
+// feishuSign 飞书机器人加签：以 timestamp + "\n" + secret 为密钥对空串做 HmacSHA256
+func feishuSign(timestamp, secret string) string {
+	mac := hmac.New(sha256.New, []byte(timestamp+"\n"+secret))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// dingTalkURL 钉钉机器人加签：以 secret 为密钥对 timestamp + "\n" + secret 做 HmacSHA256，附加到 URL 参数
+func dingTalkURL(raw, secret string, now time.Time) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	ts := strconv.FormatInt(now.UnixMilli(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "\n" + secret))
+	q := u.Query()
+	q.Set("timestamp", ts)
+	q.Set("sign", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// smtpChannel 邮件通知
+type smtpChannel struct {
+	baseChannel
+	cfg SMTPConfig
+}
+
+func (c *smtpChannel) send(ctx context.Context, a Alert) error {
+	port := c.cfg.Port
+	if port == 0 {
+		port = 587
+	}
+	addr := net.JoinHostPort(c.cfg.Host, strconv.Itoa(port))
+
+	var auth smtp.Auth
+	if c.cfg.Username != "" {
+		auth = smtp.PlainAuth("", c.cfg.Username, c.cfg.Password, c.cfg.Host)
+	}
+
+	subject := fmt.Sprintf("[api-fetch] %s", a.Title)
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", c.cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(c.cfg.To, ", "))
+	fmt.Fprintf(&msg, "Subject: =?UTF-8?B?%s?=\r\n", base64.StdEncoding.EncodeToString([]byte(subject)))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(a.Text(), "\n", "\r\n"))
+
+	// net/smtp 不支持 ctx，放到协程中并在超时后返回
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(addr, auth, c.cfg.From, c.cfg.To, msg.Bytes())
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"api-fetch/internal/api_fetch/model"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// listItemAnomalies 条目数异常记录，按发现时间倒序；key 为 API ID（fetch）或后处理函数键（process）
+func (s *Server) listItemAnomalies(c *gin.Context) {
+	filter := bson.M{}
+	for _, field := range []string{"kind", "key", "source", "category", "run_id", "reason"} {
+		if v := c.Query(field); v != "" {
+			filter[field] = v
+		}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "detected_at", Value: -1}}).SetLimit(int64(queryLimit(c, 50, 500)))
+	cur, err := s.Stores.ItemAnomalies.Find(c, filter, opts)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	out := []model.ItemAnomaly{}
+	if err := cur.All(c, &out); err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": out})
+}
@@ -0,0 +1,303 @@
+package api
+
+import (
+	"api-fetch/internal/api_fetch/model"
+	"api-fetch/internal/api_fetch/processor"
+	"api-fetch/internal/api_fetch/scheduler"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+// validateAPIInfo 校验抓取配置与调度计划
+func validateAPIInfo(a *model.APIInfo) error {
+	if err := processor.ValidateAPIInfo(a); err != nil {
+		return err
+	}
+	if _, err := scheduler.ParseSchedule(a.Schedule); err != nil {
+		return fmt.Errorf("schedule: %w", err)
+	}
+	return nil
+}
+
+// idFilter 兼容字符串 _id 与历史上手工插入的 ObjectID _id
+func idFilter(id string) bson.M {
+	if oid, err := primitive.ObjectIDFromHex(id); err == nil {
+		return bson.M{"_id": bson.M{"$in": bson.A{id, oid}}}
+	}
+	return bson.M{"_id": id}
+}
+
+// findAPI 按 ID 读取配置，不存在时返回 nil
+func (s *Server) findAPI(ctx context.Context, id string) (*model.APIInfo, error) {
+	var a model.APIInfo
+	err := s.Stores.APIs.FindOne(ctx, idFilter(id)).Decode(&a)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (s *Server) getAPI(c *gin.Context) {
+	a, err := s.findAPI(c, c.Param("id"))
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	if a == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "api not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": a})
+}
+
+func (s *Server) createAPI(c *gin.Context) {
+	var a model.APIInfo
+	if err := c.ShouldBindJSON(&a); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateAPIInfo(&a); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if a.ID == "" {
+		a.ID = primitive.NewObjectID().Hex()
+	}
+
+	if _, err := s.Stores.APIs.InsertOne(c, a); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "api id already exists"})
+			return
+		}
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	s.audit(c, "create", a.ID, nil, &a)
+	c.JSON(http.StatusCreated, gin.H{"data": a})
+}
+
+// replaceAPI PUT：整体替换配置
+func (s *Server) replaceAPI(c *gin.Context) {
+	id := c.Param("id")
+	var a model.APIInfo
+	if err := c.ShouldBindJSON(&a); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if a.ID != "" && a.ID != id {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id in body does not match url"})
+		return
+	}
+	s.updateAPI(c, "replace", id, func(*model.APIInfo) (*model.APIInfo, error) {
+		return &a, nil
+	})
+}
+
+// patchAPI PATCH：按 JSON Merge Patch（RFC 7386）修改部分字段，null 表示删除
+func (s *Server) patchAPI(c *gin.Context) {
+	var patch map[string]any
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	delete(patch, "id")
+
+	s.updateAPI(c, "patch", c.Param("id"), func(before *model.APIInfo) (*model.APIInfo, error) {
+		raw, err := json.Marshal(before)
+		if err != nil {
+			return nil, err
+		}
+		var doc map[string]any
+		if err := json.Unmarshal(raw, &doc); err != nil {
+			return nil, err
+		}
+		merged, err := json.Marshal(mergePatch(doc, patch))
+		if err != nil {
+			return nil, err
+		}
+		var after model.APIInfo
+		if err := json.Unmarshal(merged, &after); err != nil {
+			return nil, fmt.Errorf("invalid patch: %w", err)
+		}
+		return &after, nil
+	})
+}
+
+// setAPIEnabled 启用/禁用
+func (s *Server) setAPIEnabled(enabled bool) gin.HandlerFunc {
+	action := "disable"
+	if enabled {
+		action = "enable"
+	}
+	return func(c *gin.Context) {
+		s.updateAPI(c, action, c.Param("id"), func(before *model.APIInfo) (*model.APIInfo, error) {
+			after := *before
+			after.Enabled = enabled
+			return &after, nil
+		})
+	}
+}
+
+// updateAPI 读取现有配置 -> 生成新配置 -> 校验 -> 替换 -> 记录审计
+func (s *Server) updateAPI(c *gin.Context, action, id string, mutate func(before *model.APIInfo) (*model.APIInfo, error)) {
+	before, err := s.findAPI(c, id)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	if before == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "api not found"})
+		return
+	}
+
+	after, err := mutate(before)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateAPIInfo(after); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// _id 不可修改，替换文档中不带 _id
+	replacement := *after
+	replacement.ID = ""
+	res, err := s.Stores.APIs.ReplaceOne(c, idFilter(id), replacement)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	if res.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "api not found"})
+		return
+	}
+
+	after.ID = before.ID
+	s.audit(c, action, before.ID, before, after)
+	c.JSON(http.StatusOK, gin.H{"data": after})
+}
+
+func (s *Server) deleteAPI(c *gin.Context) {
+	before, err := s.findAPI(c, c.Param("id"))
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	if before == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "api not found"})
+		return
+	}
+	if _, err := s.Stores.APIs.DeleteOne(c, idFilter(before.ID)); err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	s.audit(c, "delete", before.ID, before, nil)
+	c.Status(http.StatusNoContent)
+}
+
+// listAPIAudit 查看某个 API 的变更记录，按时间倒序
+func (s *Server) listAPIAudit(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "changed_at", Value: -1}}).SetLimit(int64(limit))
+	cur, err := s.Stores.APIAudit.Find(c, bson.M{"api_id": c.Param("id")}, opts)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	out := []model.APIAudit{}
+	if err := cur.All(c, &out); err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": out})
+}
+
+// audit 记录变更；写入失败只记日志，不影响已完成的变更
+func (s *Server) audit(c *gin.Context, action, id string, before, after *model.APIInfo) {
+	entry := model.APIAudit{
+		APIID:      id,
+		Action:     action,
+		Actor:      operator(c),
+		RemoteAddr: c.ClientIP(),
+		Changes:    changedFields(before, after),
+		Before:     before,
+		After:      after,
+		ChangedAt:  time.Now().UTC(),
+	}
+	if _, err := s.Stores.APIAudit.InsertOne(c, entry); err != nil && s.Log != nil {
+		s.Log.Error("Failed to record api audit",
+			zap.String("apiId", id),
+			zap.String("action", action),
+			zap.Error(err),
+		)
+	}
+}
+
+// changedFields 对比前后配置，返回发生变化的顶层字段（按 JSON 字段名）
+func changedFields(before, after *model.APIInfo) []string {
+	toMap := func(a *model.APIInfo) map[string]any {
+		m := map[string]any{}
+		if a == nil {
+			return m
+		}
+		raw, _ := json.Marshal(a)
+		_ = json.Unmarshal(raw, &m)
+		return m
+	}
+	b, a := toMap(before), toMap(after)
+
+	var changes []string
+	for k, v := range a {
+		if !reflect.DeepEqual(b[k], v) {
+			changes = append(changes, k)
+		}
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			changes = append(changes, k)
+		}
+	}
+	sort.Strings(changes)
+	return changes
+}
+
+// mergePatch 按 RFC 7386 将 patch 合并到 doc
+func mergePatch(doc, patch map[string]any) map[string]any {
+	for k, v := range patch {
+		if v == nil {
+			delete(doc, k)
+			continue
+		}
+		pv, isObj := v.(map[string]any)
+		dv, docIsObj := doc[k].(map[string]any)
+		if isObj && docIsObj {
+			doc[k] = mergePatch(dv, pv)
+			continue
+		}
+		doc[k] = v
+	}
+	return doc
+}
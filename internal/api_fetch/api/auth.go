@@ -0,0 +1,39 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// operatorKey 认证通过后操作人在 gin.Context 中的键
+const operatorKey = "operator"
+
+// AdminConfig 管理接口认证（config.yaml 中的 admin 段）
+type AdminConfig struct {
+	Tokens map[string]string `yaml:"tokens"` // 操作人 -> 令牌，请求以 "Authorization: Bearer <令牌>" 认证
+}
+
+// requireOperator 变更类接口要求 Bearer 令牌，操作人取自令牌对应的名字；未配置令牌时一律拒绝
+func (s *Server) requireOperator() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if ok && token != "" {
+			for name, want := range s.Admin.Tokens {
+				if want != "" && subtle.ConstantTimeCompare([]byte(token), []byte(want)) == 1 {
+					c.Set(operatorKey, name)
+					c.Next()
+					return
+				}
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+	}
+}
+
+// operator 当前请求的操作人
+func operator(c *gin.Context) string {
+	return c.GetString(operatorKey)
+}
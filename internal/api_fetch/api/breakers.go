@@ -0,0 +1,21 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// listBreakers 熔断器状态，熔断中的排在前面；只包含出现过上游故障的 host（或 API）
+func (s *Server) listBreakers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"data": s.Scheduler.Breakers()})
+}
+
+// resetBreaker 手动关闭熔断器，上游恢复后无需等待探测
+func (s *Server) resetBreaker(c *gin.Context) {
+	if !s.Scheduler.ResetBreaker(c.Param("key")) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "breaker not found"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
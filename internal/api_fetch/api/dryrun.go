@@ -0,0 +1,37 @@
+package api
+
+import (
+	"api-fetch/internal/api_fetch/model"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// testAPI 试运行库中已有的 API 配置，不写库
+func (s *Server) testAPI(c *gin.Context) {
+	a, err := s.findAPI(c, c.Param("id"))
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	if a == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "api not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": s.Scheduler.DryRun(c, a, true)})
+}
+
+// testInlineAPI 试运行请求体中的 API 配置，用于接入新数据源前调试；
+// 请求体可指向任意地址，因此不解析 secret 引用，需要密钥的配置请入库后用 testAPI 试运行
+func (s *Server) testInlineAPI(c *gin.Context) {
+	var a model.APIInfo
+	if err := c.ShouldBindJSON(&a); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateAPIInfo(&a); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": s.Scheduler.DryRun(c, &a, false)})
+}
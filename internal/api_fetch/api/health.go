@@ -0,0 +1,299 @@
+package api
+
+import (
+	"api-fetch/internal/api_fetch/model"
+	"api-fetch/internal/api_fetch/scheduler"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// 健康检查默认参数
+const (
+	defaultHealthWindow     = 24 * time.Hour
+	defaultStaleIntervals   = 3
+	lastAttemptLookbackDays = 30 // 最近一次成功/失败只在这段时间内查找，窗口更长时取窗口
+)
+
+// SourceHealth 单个 API 的健康状况
+type SourceHealth struct {
+	APIID          string     `json:"api_id"`
+	Source         string     `json:"source"`
+	Category       string     `json:"category"`
+	InfoType       string     `json:"info_type"`
+	Enabled        bool       `json:"enabled"`
+	Schedule       string     `json:"schedule"`
+	LastSuccessAt  *time.Time `json:"last_success_at,omitempty"`
+	LastErrorAt    *time.Time `json:"last_error_at,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	LastErrorClass string     `json:"last_error_class,omitempty"`
+	Attempts       int        `json:"attempts"`                 // 窗口内的尝试次数
+	Successes      int        `json:"successes"`                // 窗口内的成功次数
+	SuccessRate    *float64   `json:"success_rate,omitempty"`   // 窗口内没有尝试时为空
+	LatencyP50MS   int64      `json:"latency_p50_ms,omitempty"` // 窗口内成功尝试的 HTTP 耗时
+	LatencyP95MS   int64      `json:"latency_p95_ms,omitempty"`
+	AvgItems       *float64   `json:"avg_items,omitempty"` // 窗口内成功尝试的平均条目数
+	MissedRuns     int        `json:"missed_runs"`         // 最近一次成功后经过的计划执行次数
+	Stale          bool       `json:"stale"`
+	StaleReason    string     `json:"stale_reason,omitempty"`
+}
+
+// lastAttempt 每个 API 最近一次成功/失败的尝试
+type lastAttempt struct {
+	APIID      string    `bson:"_id"`
+	At         time.Time `bson:"at"`
+	Error      string    `bson:"error"`
+	ErrorClass string    `bson:"error_class"`
+}
+
+// sourcesHealth GET /sources/health?window=24h&stale_intervals=3&source=&category=
+// 按 fetch_attempts 汇总每个 API 的成功率、耗时与数据新鲜度，stale 的排在前面
+func (s *Server) sourcesHealth(c *gin.Context) {
+	window := defaultHealthWindow
+	if v := c.Query("window"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "window must be a positive duration such as 24h"})
+			return
+		}
+		window = d
+	}
+	staleIntervals := defaultStaleIntervals
+	if v := c.Query("stale_intervals"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "stale_intervals must be a positive integer"})
+			return
+		}
+		staleIntervals = n
+	}
+
+	apiFilter := bson.M{}
+	if v := c.Query("source"); v != "" {
+		apiFilter["source"] = v
+	}
+	if v := c.Query("category"); v != "" {
+		apiFilter["category"] = v
+	}
+	var apis []model.APIInfo
+	cur, err := s.Stores.APIs.Find(c, apiFilter)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	if err := cur.All(c, &apis); err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now().UTC()
+	lookback := max(window, lastAttemptLookbackDays*24*time.Hour)
+	apiIDs := make([]string, 0, len(apis))
+	for _, a := range apis {
+		apiIDs = append(apiIDs, a.ID)
+	}
+	lastSuccess, err := s.lastAttempts(c, true, apiIDs, now.Add(-lookback))
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	lastError, err := s.lastAttempts(c, false, apiIDs, now.Add(-lookback))
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	recent, err := s.recentAttempts(c, apiIDs, now.Add(-window))
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	out := make([]SourceHealth, 0, len(apis))
+	for i := range apis {
+		a := &apis[i]
+		h := SourceHealth{
+			APIID:    a.ID,
+			Source:   a.Source,
+			Category: a.Category,
+			InfoType: a.InfoType,
+			Enabled:  a.Enabled,
+		}
+		if la, ok := lastSuccess[a.ID]; ok {
+			at := la.At
+			h.LastSuccessAt = &at
+		}
+		if la, ok := lastError[a.ID]; ok {
+			at := la.At
+			h.LastErrorAt = &at
+			h.LastError = la.Error
+			h.LastErrorClass = la.ErrorClass
+		}
+		summarizeAttempts(&h, recent[a.ID])
+
+		sched, err := scheduler.ParseSchedule(a.Schedule)
+		if err != nil {
+			h.Schedule = "invalid: " + err.Error()
+		} else {
+			h.Schedule = sched.String()
+			markStale(&h, sched, now, staleIntervals, lookback)
+		}
+		out = append(out, h)
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Stale != out[j].Stale {
+			return out[i].Stale
+		}
+		if out[i].Source != out[j].Source {
+			return out[i].Source < out[j].Source
+		}
+		return out[i].Category < out[j].Category
+	})
+	c.JSON(http.StatusOK, gin.H{
+		"window":          window.String(),
+		"stale_intervals": staleIntervals,
+		"generated_at":    now,
+		"data":            out,
+	})
+}
+
+// lastAttempts apiIDs 中每个 API 在 since 之后最近一次成功（或失败）的尝试
+func (s *Server) lastAttempts(c *gin.Context, success bool, apiIDs []string, since time.Time) (map[string]lastAttempt, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"success":    success,
+			"api_id":     bson.M{"$in": apiIDs},
+			"started_at": bson.M{"$gte": since},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "api_id", Value: 1}, {Key: "started_at", Value: -1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":         "$api_id",
+			"at":          bson.M{"$first": "$finished_at"},
+			"error":       bson.M{"$first": "$error"},
+			"error_class": bson.M{"$first": "$error_class"},
+		}}},
+	}
+	cur, err := s.Stores.FetchAttempts.Aggregate(c, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var rows []lastAttempt
+	if err := cur.All(c, &rows); err != nil {
+		return nil, err
+	}
+	out := make(map[string]lastAttempt, len(rows))
+	for _, r := range rows {
+		out[r.APIID] = r
+	}
+	return out, nil
+}
+
+// recentAttempts apiIDs 在窗口内的尝试，按 API 分组
+func (s *Server) recentAttempts(c *gin.Context, apiIDs []string, since time.Time) (map[string][]model.FetchAttempt, error) {
+	opts := options.Find().SetProjection(bson.M{
+		"api_id": 1, "success": 1, "latency_ms": 1, "item_count": 1,
+	})
+	cur, err := s.Stores.FetchAttempts.Find(c, bson.M{
+		"api_id":     bson.M{"$in": apiIDs},
+		"started_at": bson.M{"$gte": since},
+	}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var rows []model.FetchAttempt
+	if err := cur.All(c, &rows); err != nil {
+		return nil, err
+	}
+	out := make(map[string][]model.FetchAttempt)
+	for _, r := range rows {
+		out[r.APIID] = append(out[r.APIID], r)
+	}
+	return out, nil
+}
+
+// summarizeAttempts 计算成功率、耗时分位数与平均条目数
+func summarizeAttempts(h *SourceHealth, attempts []model.FetchAttempt) {
+	h.Attempts = len(attempts)
+	if h.Attempts == 0 {
+		return
+	}
+
+	var latencies []int64
+	var items, itemSamples int
+	for _, a := range attempts {
+		if !a.Success {
+			continue
+		}
+		h.Successes++
+		latencies = append(latencies, a.LatencyMS)
+		if a.ItemCount >= 0 {
+			items += a.ItemCount
+			itemSamples++
+		}
+	}
+
+	rate := float64(h.Successes) / float64(h.Attempts)
+	h.SuccessRate = &rate
+	if len(latencies) > 0 {
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		h.LatencyP50MS = percentile(latencies, 0.50)
+		h.LatencyP95MS = percentile(latencies, 0.95)
+	}
+	if itemSamples > 0 {
+		avg := float64(items) / float64(itemSamples)
+		h.AvgItems = &avg
+	}
+}
+
+// markStale 最近一次成功后已错过 n 次计划执行即视为 stale；lookback 内没有成功或没有任何尝试的也视为 stale
+func markStale(h *SourceHealth, sched scheduler.Schedule, now time.Time, n int, lookback time.Duration) {
+	if !h.Enabled {
+		return
+	}
+	if h.LastSuccessAt == nil {
+		h.Stale = true
+		if h.LastErrorAt != nil {
+			h.StaleReason = "no successful fetch in the last " + shortDuration(lookback)
+		} else {
+			h.StaleReason = "no fetch attempts in the last " + shortDuration(lookback)
+		}
+		return
+	}
+	h.MissedRuns = scheduler.MissedRuns(sched, *h.LastSuccessAt, now, n)
+	if h.MissedRuns >= n {
+		h.Stale = true
+		h.StaleReason = "no successful fetch in the last " + strconv.Itoa(n) + " scheduled runs"
+	}
+}
+
+// shortDuration 去掉 time.Duration 字符串末尾多余的 0m0s，如 720h0m0s -> 720h
+func shortDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// percentile 最近秩法，sorted 需已升序
+func percentile(sorted []int64, p float64) int64 {
+	idx := int(math.Ceil(float64(len(sorted))*p)) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
@@ -0,0 +1,163 @@
+package api
+
+import (
+	"api-fetch/internal/api_fetch/model"
+	"api-fetch/internal/api_fetch/processor"
+	"api-fetch/internal/api_fetch/scheduler"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// processRequest 手动后处理请求，key 与 source/category/info_type 二选一，date 默认当天
+type processRequest struct {
+	Key      string `json:"key"`
+	Source   string `json:"source"`
+	Category string `json:"category"`
+	InfoType string `json:"info_type"`
+	Date     string `json:"date"` // YYYY-MM-DD（Asia/Shanghai）
+}
+
+// triggerFetch 手动触发抓取：请求体可为空（全部启用 API），或指定 api_id / source / category
+func (s *Server) triggerFetch(c *gin.Context) {
+	var filter scheduler.FetchFilter
+	if err := c.ShouldBindJSON(&filter); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	runID, err := s.Scheduler.TriggerFetch(filter)
+	if err != nil {
+		c.JSON(triggerErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"run_id": runID})
+}
+
+// triggerProcess 手动触发某个处理键在指定日期的后处理
+func (s *Server) triggerProcess(c *gin.Context) {
+	var req processRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	key := req.Key
+	if key == "" {
+		if req.Source == "" || req.Category == "" || req.InfoType == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "key or source/category/info_type is required"})
+			return
+		}
+		key = processor.DataProcessorConfig{Source: req.Source, Category: req.Category, InfoType: req.InfoType}.Key()
+	}
+
+	loc, _ := time.LoadLocation("Asia/Shanghai")
+	date := time.Now().In(loc)
+	if req.Date != "" {
+		d, err := time.ParseInLocation("2006-01-02", req.Date, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "date must be YYYY-MM-DD"})
+			return
+		}
+		date = d
+	}
+
+	runID, err := s.Scheduler.TriggerProcess(key, date)
+	if err != nil {
+		c.JSON(triggerErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"run_id": runID})
+}
+
+func (s *Server) getRun(c *gin.Context) {
+	run, err := s.Scheduler.GetRun(c, c.Param("id"))
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	if run == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "run not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": run})
+}
+
+// listRuns ?kind=fetch|process&trigger=schedule|manual&status=&limit=50
+func (s *Server) listRuns(c *gin.Context) {
+	q := scheduler.RunQuery{
+		Kind:    c.Query("kind"),
+		Trigger: c.Query("trigger"),
+		Status:  c.Query("status"),
+	}
+	runs, err := s.Scheduler.ListRuns(c, q, queryLimit(c, 50, 500))
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": runs})
+}
+
+// listRunAttempts 某次运行中的全部抓取尝试
+func (s *Server) listRunAttempts(c *gin.Context) {
+	s.findAttempts(c, bson.M{"run_id": c.Param("id")}, 1000)
+}
+
+// listAttempts 查询抓取尝试，如某来源最近一次成功：?source=X&success=true&limit=1
+func (s *Server) listAttempts(c *gin.Context) {
+	filter := bson.M{}
+	for _, field := range []string{"api_id", "source", "category", "info_type", "error_class", "anomaly"} {
+		if v := c.Query(field); v != "" {
+			filter[field] = v
+		}
+	}
+	if v := c.Query("success"); v != "" {
+		ok, err := strconv.ParseBool(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "success must be true or false"})
+			return
+		}
+		filter["success"] = ok
+	}
+	s.findAttempts(c, filter, queryLimit(c, 50, 500))
+}
+
+func (s *Server) findAttempts(c *gin.Context, filter bson.M, limit int) {
+	opts := options.Find().SetSort(bson.D{{Key: "started_at", Value: -1}}).SetLimit(int64(limit))
+	cur, err := s.Stores.FetchAttempts.Find(c, filter, opts)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	out := []model.FetchAttempt{}
+	if err := cur.All(c, &out); err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": out})
+}
+
+// queryLimit 读取 ?limit=，超出范围时使用默认值
+func queryLimit(c *gin.Context, def, max int) int {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(def)))
+	if limit <= 0 || limit > max {
+		return def
+	}
+	return limit
+}
+
+func triggerErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, scheduler.ErrNotRunning):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, scheduler.ErrNoMatchingAPIs), errors.Is(err, scheduler.ErrUnknownProcessor):
+		return http.StatusNotFound
+	default:
+		return 500
+	}
+}
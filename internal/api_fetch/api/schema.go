@@ -0,0 +1,63 @@
+package api
+
+import (
+	"api-fetch/internal/api_fetch/model"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// getSchema 已学习的响应结构指纹
+func (s *Server) getSchema(c *gin.Context) {
+	var fp model.SchemaFingerprint
+	err := s.Stores.Schemas.FindOne(c, bson.M{"_id": c.Param("api_id")}).Decode(&fp)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "schema not learned yet"})
+		return
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": fp})
+}
+
+// resetSchema 删除已学习的结构，下次抓取时重新学习（确认上游变化后使用）
+func (s *Server) resetSchema(c *gin.Context) {
+	res, err := s.Stores.Schemas.DeleteOne(c, bson.M{"_id": c.Param("api_id")})
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	if res.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "schema not learned yet"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// listSchemaDrifts ?api_id=&source=&category=&run_id=&limit=50，按发现时间倒序
+func (s *Server) listSchemaDrifts(c *gin.Context) {
+	filter := bson.M{}
+	for _, field := range []string{"api_id", "source", "category", "run_id"} {
+		if v := c.Query(field); v != "" {
+			filter[field] = v
+		}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "detected_at", Value: -1}}).SetLimit(int64(queryLimit(c, 50, 500)))
+	cur, err := s.Stores.SchemaDrifts.Find(c, filter, opts)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	out := []model.SchemaDrift{}
+	if err := cur.All(c, &out); err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": out})
+}
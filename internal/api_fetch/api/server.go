@@ -12,16 +12,50 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 
 	"api-fetch/internal/api_fetch/helper"
+	"api-fetch/internal/api_fetch/metrics"
+	"api-fetch/internal/api_fetch/scheduler"
+	"go.uber.org/zap"
 )
 
 type Server struct {
 	Stores *helper.Stores
+	Log    *zap.Logger
+
+	Scheduler *scheduler.Scheduler // 试运行、手动触发等管理操作
+	Admin     AdminConfig          // 变更类接口的认证令牌
 }
 
 func (s *Server) Router() *gin.Engine {
 	r := gin.Default()
+	r.Use(metrics.GinMiddleware())
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
+	// 变更配置、发出请求、触发任务的接口需要认证
+	admin := r.Group("", s.requireOperator())
 	r.GET("/apis", s.listAPIs)
-	r.GET("/contents", s.listContents) // ?date=YYYY-MM-DD&source=&category=&page=1&limit=20
+	admin.POST("/apis", s.createAPI)
+	r.GET("/apis/:id", s.getAPI)
+	admin.PUT("/apis/:id", s.replaceAPI)
+	admin.PATCH("/apis/:id", s.patchAPI)
+	admin.DELETE("/apis/:id", s.deleteAPI)
+	admin.POST("/apis/:id/enable", s.setAPIEnabled(true))
+	admin.POST("/apis/:id/disable", s.setAPIEnabled(false))
+	r.GET("/apis/:id/audit", s.listAPIAudit) // ?limit=50
+	admin.POST("/apis/:id/test", s.testAPI)
+	admin.POST("/apis/test", s.testInlineAPI)
+	admin.POST("/runs/fetch", s.triggerFetch)
+	admin.POST("/runs/process", s.triggerProcess)
+	r.GET("/runs", s.listRuns) // ?kind=&trigger=&status=&limit=50
+	r.GET("/runs/:id", s.getRun)
+	r.GET("/runs/:id/attempts", s.listRunAttempts)
+	r.GET("/attempts", s.listAttempts)       // ?api_id=&source=&category=&info_type=&success=&error_class=&anomaly=&limit=50
+	r.GET("/anomalies", s.listItemAnomalies) // ?kind=&key=&source=&category=&run_id=&reason=&limit=50
+	r.GET("/breakers", s.listBreakers)
+	admin.DELETE("/breakers/:key", s.resetBreaker)
+	r.GET("/sources/health", s.sourcesHealth) // ?window=24h&stale_intervals=3&source=&category=
+	r.GET("/schemas/:api_id", s.getSchema)
+	admin.DELETE("/schemas/:api_id", s.resetSchema)
+	r.GET("/schema-drifts", s.listSchemaDrifts) // ?api_id=&source=&category=&run_id=&limit=50
+	r.GET("/contents", s.listContents)          // ?date=YYYY-MM-DD&source=&category=&page=1&limit=20
 	return r
 }
 
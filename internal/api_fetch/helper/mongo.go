@@ -11,8 +11,18 @@ import (
 )
 
 type Stores struct {
-	DB   *mongo.Database
-	APIs *mongo.Collection // 固定集合：apis
+	DB             *mongo.Database
+	APIs           *mongo.Collection // 固定集合：apis
+	TransformRules *mongo.Collection // 固定集合：transform_rules（声明式后处理规则）
+	Articles       *mongo.Collection // 固定集合：articles（去重后的条目）
+	APIAudit       *mongo.Collection // 固定集合：api_audit（apis 配置变更记录）
+	FetchRuns      *mongo.Collection // 固定集合：fetch_runs（抓取/后处理运行记录）
+	FetchAttempts  *mongo.Collection // 固定集合：fetch_attempts（每个 API 每次尝试的结果）
+	Schemas        *mongo.Collection // 固定集合：schema_fingerprints（各 API 响应的结构指纹）
+	SchemaDrifts   *mongo.Collection // 固定集合：schema_drifts（响应结构变化记录）
+	ItemBaselines  *mongo.Collection // 固定集合：item_baselines（最近若干次的条目数）
+	ItemAnomalies  *mongo.Collection // 固定集合：item_anomalies（条目数异常记录）
+	FetchState     *mongo.Collection // 固定集合：fetch_state（各 API 上次保存的 ETag、内容哈希）
 }
 
 func MustMongo(ctx context.Context, host, dbname, username, password, authSource string) *Stores {
@@ -34,8 +44,18 @@ func MustMongo(ctx context.Context, host, dbname, username, password, authSource
 
 	db := cli.Database(dbname)
 	s := &Stores{
-		DB:   db,
-		APIs: db.Collection("apis"),
+		DB:             db,
+		APIs:           db.Collection("apis"),
+		TransformRules: db.Collection("transform_rules"),
+		Articles:       db.Collection("articles"),
+		APIAudit:       db.Collection("api_audit"),
+		FetchRuns:      db.Collection("fetch_runs"),
+		FetchAttempts:  db.Collection("fetch_attempts"),
+		Schemas:        db.Collection("schema_fingerprints"),
+		SchemaDrifts:   db.Collection("schema_drifts"),
+		ItemBaselines:  db.Collection("item_baselines"),
+		ItemAnomalies:  db.Collection("item_anomalies"),
+		FetchState:     db.Collection("fetch_state"),
 	}
 	ensureIndexes(ctx, s)
 	return s
@@ -50,8 +70,77 @@ func ensureIndexes(ctx context.Context, s *Stores) {
 		{Keys: bson.D{{Key: "info_type", Value: 1}}},
 		{Keys: bson.D{{Key: "processed", Value: 1}}},
 	})
+
+	// transform_rules: 每个 source/category/info_type 只能有一条规则
+	_, _ = s.TransformRules.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "source", Value: 1},
+			{Key: "category", Value: 1},
+			{Key: "info_type", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	})
+
+	// articles: 同一来源下按去重键唯一
+	_, _ = s.Articles.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "source", Value: 1},
+				{Key: "category", Value: 1},
+				{Key: "info_type", Value: 1},
+				{Key: "dedup_key", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "last_seen", Value: -1}}},
+	})
+
+	// api_audit: 按 API 查询变更历史
+	_, _ = s.APIAudit.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "api_id", Value: 1}, {Key: "changed_at", Value: -1}},
+	})
+
+	// fetch_runs: 按时间倒序列出，保留 90 天
+	_, _ = s.FetchRuns.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "kind", Value: 1}, {Key: "started_at", Value: -1}}},
+		{
+			Keys:    bson.D{{Key: "started_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(fetchHistoryTTL),
+		},
+	})
+
+	// fetch_attempts: 按运行、API、来源查询，保留 90 天
+	_, _ = s.FetchAttempts.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "run_id", Value: 1}}},
+		{Keys: bson.D{{Key: "api_id", Value: 1}, {Key: "started_at", Value: -1}}},
+		{Keys: bson.D{{Key: "source", Value: 1}, {Key: "category", Value: 1}, {Key: "started_at", Value: -1}}},
+		{
+			Keys:    bson.D{{Key: "started_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(fetchHistoryTTL),
+		},
+	})
+
+	// schema_drifts: 按 API、来源查询
+	_, _ = s.SchemaDrifts.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "api_id", Value: 1}, {Key: "detected_at", Value: -1}}},
+		{Keys: bson.D{{Key: "source", Value: 1}, {Key: "detected_at", Value: -1}}},
+	})
+
+	// item_anomalies: 按运行、来源查询，保留 90 天
+	_, _ = s.ItemAnomalies.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "run_id", Value: 1}}},
+		{Keys: bson.D{{Key: "key", Value: 1}, {Key: "detected_at", Value: -1}}},
+		{Keys: bson.D{{Key: "source", Value: 1}, {Key: "detected_at", Value: -1}}},
+		{
+			Keys:    bson.D{{Key: "detected_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(fetchHistoryTTL),
+		},
+	})
 }
 
+// fetchHistoryTTL 运行记录与抓取记录的保留时间（秒）
+const fetchHistoryTTL = 90 * 24 * 3600
+
 // -------- 按日期分表（collection）工具 --------
 
 // 替换原来的 tokyo 变量为 shanghai
@@ -0,0 +1,174 @@
+// Package metrics 定义服务导出的 Prometheus 指标，注册在默认 Registry 上，由 /metrics 暴露
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "api_fetch"
+
+// sourceLabels 数据源维度的标签
+var sourceLabels = []string{"source", "category", "info_type"}
+
+var (
+	fetchAttempts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "fetch_attempts_total",
+		Help:      "API fetch attempts by outcome (success|failure) and error class.",
+	}, append(sourceLabels, "outcome", "error_class"))
+
+	fetchDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "fetch_http_duration_seconds",
+		Help:      "Upstream HTTP request latency per page, including reading the body.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 5, 10, 30},
+	}, sourceLabels)
+
+	responseBytes = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "fetch_response_bytes",
+		Help:      "Upstream response body size per page.",
+		Buckets:   prometheus.ExponentialBuckets(1024, 4, 8), // 1KB ~ 16MB
+	}, sourceLabels)
+
+	retriesScheduled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "fetch_retries_scheduled_total",
+		Help:      "Async retries scheduled after a failed attempt.",
+	}, sourceLabels)
+
+	retriesExhausted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "fetch_retries_exhausted_total",
+		Help:      "APIs that gave up after reaching the maximum number of attempts.",
+	}, sourceLabels)
+
+	documentsInserted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "documents_inserted_total",
+		Help:      "Raw documents inserted into rawdata_* collections.",
+	}, sourceLabels)
+
+	processedDocuments = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "processor_documents_total",
+		Help:      "Raw documents handled by DataProcessor by outcome (processed|failed).",
+	}, append(sourceLabels, "outcome"))
+
+	fetchUnchanged = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "fetch_unchanged_total",
+		Help:      "Successful fetches not stored because the content was unchanged, by reason (not_modified|same_hash).",
+	}, append(sourceLabels, "reason"))
+
+	fetchInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "fetch_in_flight",
+		Help:      "Fetch attempts currently holding a concurrency slot.",
+	})
+
+	rateLimitWait = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "fetch_rate_limit_wait_seconds",
+		Help:      "Time a request waited for the per-host token bucket.",
+		Buckets:   []float64{0.01, 0.1, 0.5, 1, 5, 15, 60},
+	}, []string{"host"})
+
+	breakerState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "circuit_breaker_state",
+		Help:      "Circuit breaker state per key (host or API ID): 0 closed, 1 half-open, 2 open.",
+	}, []string{"key"})
+
+	itemAnomalies = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "item_anomalies_total",
+		Help:      "Item counts flagged against the rolling baseline by kind (fetch|process) and reason (zero_items|item_drop).",
+	}, append(sourceLabels, "kind", "reason"))
+
+	schedulerLag = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "scheduler_lag_seconds",
+		Help:      "Delay between the planned and the actual start of a scheduled fetch.",
+		Buckets:   []float64{0.1, 0.5, 1, 5, 15, 60, 300, 900},
+	}, sourceLabels)
+)
+
+// FetchAttempt 记录一次抓取尝试的结果，errorClass 在成功时为空
+func FetchAttempt(source, category, infoType string, success bool, errorClass string) {
+	outcome := "success"
+	if !success {
+		outcome = "failure"
+	}
+	fetchAttempts.WithLabelValues(source, category, infoType, outcome, errorClass).Inc()
+}
+
+// FetchHTTP 记录单页请求的耗时与响应体大小
+func FetchHTTP(source, category, infoType string, latency time.Duration, bytes int) {
+	fetchDuration.WithLabelValues(source, category, infoType).Observe(latency.Seconds())
+	responseBytes.WithLabelValues(source, category, infoType).Observe(float64(bytes))
+}
+
+// RetryScheduled 安排了一次异步重试
+func RetryScheduled(source, category, infoType string) {
+	retriesScheduled.WithLabelValues(source, category, infoType).Inc()
+}
+
+// RetriesExhausted 重试次数用尽
+func RetriesExhausted(source, category, infoType string) {
+	retriesExhausted.WithLabelValues(source, category, infoType).Inc()
+}
+
+// DocumentsInserted 写入原始数据分表的文档数
+func DocumentsInserted(source, category, infoType string, n int) {
+	documentsInserted.WithLabelValues(source, category, infoType).Add(float64(n))
+}
+
+// DocumentsProcessed 后处理成功与失败的文档数
+func DocumentsProcessed(source, category, infoType string, processed, failed int) {
+	processedDocuments.WithLabelValues(source, category, infoType, "processed").Add(float64(processed))
+	processedDocuments.WithLabelValues(source, category, infoType, "failed").Add(float64(failed))
+}
+
+// FetchUnchanged 内容未变、未保存的抓取
+func FetchUnchanged(source, category, infoType, reason string) {
+	fetchUnchanged.WithLabelValues(source, category, infoType, reason).Inc()
+}
+
+// FetchInFlight 占用抓取槽位的尝试数增减
+func FetchInFlight(delta int) {
+	fetchInFlight.Add(float64(delta))
+}
+
+// RateLimitWait 请求等待 host 令牌桶的时间
+func RateLimitWait(host string, wait time.Duration) {
+	rateLimitWait.WithLabelValues(host).Observe(wait.Seconds())
+}
+
+// BreakerState 熔断器状态变化，state 为 closed | half_open | open
+func BreakerState(key, state string) {
+	v := 0.0
+	switch state {
+	case "half_open":
+		v = 1
+	case "open":
+		v = 2
+	}
+	breakerState.WithLabelValues(key).Set(v)
+}
+
+// ItemAnomaly 条目数相对基线异常
+func ItemAnomaly(source, category, infoType, kind, reason string) {
+	itemAnomalies.WithLabelValues(source, category, infoType, kind, reason).Inc()
+}
+
+// SchedulerLag 计划执行时间与实际开始时间之差
+func SchedulerLag(source, category, infoType string, lag time.Duration) {
+	if lag < 0 {
+		lag = 0
+	}
+	schedulerLag.WithLabelValues(source, category, infoType).Observe(lag.Seconds())
+}
@@ -7,6 +7,7 @@ type APIInfo struct {
 	URL             string            `bson:"url" json:"url"`
 	Headers         map[string]string `bson:"headers,omitempty" json:"headers,omitempty"`
 	Params          map[string]string `bson:"params,omitempty" json:"params,omitempty"` // ✅ 新增请求参数
+	Body            string            `bson:"body,omitempty" json:"body,omitempty"`     // POST/JSON 的原始请求体，设置后替代 params；与 url、params、headers 一样支持模板
 	Required        map[string]any    `bson:"required,omitempty" json:"required,omitempty"`
 	Source          string            `bson:"source" json:"source"`       // 来源
 	Category        string            `bson:"category" json:"category"`   // 信息分类
@@ -14,4 +15,100 @@ type APIInfo struct {
 	Enabled         bool              `bson:"enabled" json:"enabled"`
 	UseFullResponse bool              `bson:"use_full_response,omitempty" json:"use_full_response,omitempty"` // 是否使用完整响应
 	DataField       string            `bson:"data_field,omitempty" json:"data_field,omitempty"`
+	Schedule        *Schedule         `bson:"schedule,omitempty" json:"schedule,omitempty"`               // 抓取计划，为空时使用默认的 3 小时锚点
+	Pagination      *Pagination       `bson:"pagination,omitempty" json:"pagination,omitempty"`           // 分页配置，为空时只抓第一页
+	ResponseFormat  string            `bson:"response_format,omitempty" json:"response_format,omitempty"` // "json"（默认）| "xml" | "rss" | "atom" | "html"
+	HTML            *HTMLExtract      `bson:"html,omitempty" json:"html,omitempty"`                       // HTML 页面抽取规则，response_format 为 html 时必填
+	Charset         string            `bson:"charset,omitempty" json:"charset,omitempty"`                 // 强制指定响应编码（如 gbk、big5），为空时自动检测
+	Retry           *RetryPolicy      `bson:"retry,omitempty" json:"retry,omitempty"`                     // 失败重试策略，为空时使用默认策略
+	AlwaysStore     bool              `bson:"always_store,omitempty" json:"always_store,omitempty"`       // 每次都保存，不发送条件请求也不按内容去重
+	Auth            *Auth             `bson:"auth,omitempty" json:"auth,omitempty"`                       // 请求认证，为空时只使用 headers
+}
+
+// Auth 请求认证配置，按 type 填写对应的子配置；字段支持模板，client_secret、hmac.secret 与 api_key.keys 须为 {{secret "NAME"}} 引用
+type Auth struct {
+	Type   string      `bson:"type" json:"type"` // "oauth2" | "hmac" | "api_key"
+	OAuth2 *OAuth2Auth `bson:"oauth2,omitempty" json:"oauth2,omitempty"`
+	HMAC   *HMACAuth   `bson:"hmac,omitempty" json:"hmac,omitempty"`
+	APIKey *APIKeyAuth `bson:"api_key,omitempty" json:"api_key,omitempty"`
+}
+
+// OAuth2Auth OAuth2 client credentials，令牌缓存到过期前 1 分钟，上游返回 401 时立即换新令牌重发
+type OAuth2Auth struct {
+	TokenURL     string            `bson:"token_url" json:"token_url"`
+	ClientID     string            `bson:"client_id" json:"client_id"`
+	ClientSecret string            `bson:"client_secret" json:"client_secret"`
+	Scopes       []string          `bson:"scopes,omitempty" json:"scopes,omitempty"`
+	Params       map[string]string `bson:"params,omitempty" json:"params,omitempty"`         // 令牌请求的附加参数，如 audience
+	AuthStyle    string            `bson:"auth_style,omitempty" json:"auth_style,omitempty"` // "header"（默认，HTTP Basic）| "params"（client_id、client_secret 放在表单中）
+}
+
+// HMACAuth 请求签名：对 method、path、时间戳（及可选的 nonce、请求体哈希）做 HMAC-SHA256
+type HMACAuth struct {
+	KeyID           string `bson:"key_id,omitempty" json:"key_id,omitempty"`                     // AccessKey ID，可在 value 中以 {{.KeyID}} 引用
+	Secret          string `bson:"secret" json:"secret"`                                         // 签名密钥
+	Header          string `bson:"header,omitempty" json:"header,omitempty"`                     // 签名写入的请求头，默认 Authorization
+	Value           string `bson:"value,omitempty" json:"value,omitempty"`                       // 请求头的值模板，默认 "HMAC-SHA256 {{.KeyID}}:{{.Signature}}"
+	TimestampHeader string `bson:"timestamp_header,omitempty" json:"timestamp_header,omitempty"` // 时间戳请求头，默认 X-Timestamp
+	TimestampFormat string `bson:"timestamp_format,omitempty" json:"timestamp_format,omitempty"` // "unix"（默认）| "unix_milli" | "rfc3339" | Go layout
+	NonceHeader     string `bson:"nonce_header,omitempty" json:"nonce_header,omitempty"`         // 设置时附带随机串并参与签名
+	SignBody        bool   `bson:"sign_body,omitempty" json:"sign_body,omitempty"`               // 签名串附加请求体的 SHA-256
+	Encoding        string `bson:"encoding,omitempty" json:"encoding,omitempty"`                 // 签名编码 "hex"（默认）| "base64"
+}
+
+// APIKeyAuth 多个 API Key 轮换：持续使用当前密钥，配额用尽时停用一段时间并切换到下一个
+type APIKeyAuth struct {
+	Keys           []string `bson:"keys" json:"keys"`
+	In             string   `bson:"in,omitempty" json:"in,omitempty"`                           // "header"（默认）| "query"
+	Name           string   `bson:"name" json:"name"`                                           // 请求头或参数名
+	Prefix         string   `bson:"prefix,omitempty" json:"prefix,omitempty"`                   // 请求头值前缀，如 "Bearer "
+	RotateStatuses []int    `bson:"rotate_statuses,omitempty" json:"rotate_statuses,omitempty"` // 视为配额用尽的状态码，默认 429
+	Cooldown       string   `bson:"cooldown,omitempty" json:"cooldown,omitempty"`               // 配额用尽的密钥停用时长，默认 "1h"；响应带 Retry-After 时以其为准
+}
+
+// RetryPolicy 失败重试策略，未填的字段使用默认值
+type RetryPolicy struct {
+	MaxAttempts   int      `bson:"max_attempts,omitempty" json:"max_attempts,omitempty"`     // 含首次在内的最多尝试次数，默认 5，1 表示不重试
+	BaseDelay     string   `bson:"base_delay,omitempty" json:"base_delay,omitempty"`         // 首次重试的延迟，之后每次翻倍，默认 "15s"
+	MaxDelay      string   `bson:"max_delay,omitempty" json:"max_delay,omitempty"`           // 单次延迟上限，默认 "10m"
+	Jitter        *float64 `bson:"jitter,omitempty" json:"jitter,omitempty"`                 // 随机抖动比例 0~1，默认 0.1，0 表示不抖动
+	RetryOn       []string `bson:"retry_on,omitempty" json:"retry_on,omitempty"`             // 可重试的错误分类，默认 network、timeout、http_status、storage
+	RetryStatuses []int    `bson:"retry_statuses,omitempty" json:"retry_statuses,omitempty"` // http_status 错误中可重试的状态码，默认 408、425、429、500、502、503、504
+}
+
+// Schedule 抓取计划，Cron 与 Interval 二选一
+type Schedule struct {
+	Cron     string `bson:"cron,omitempty" json:"cron,omitempty"`         // 标准 5 段 cron 表达式，如 "0 8 * * *"，也支持 @daily 等描述符
+	Interval string `bson:"interval,omitempty" json:"interval,omitempty"` // 固定间隔，如 "5m"、"1h"，按整点对齐
+	Timezone string `bson:"timezone,omitempty" json:"timezone,omitempty"` // 时区，默认 Asia/Shanghai
+}
+
+// Pagination 分页抓取配置
+type Pagination struct {
+	Type         string `bson:"type" json:"type"`                                         // "page" | "offset" | "cursor" | "link"
+	Param        string `bson:"param,omitempty" json:"param,omitempty"`                   // 页码/偏移量/游标的请求参数名
+	SizeParam    string `bson:"size_param,omitempty" json:"size_param,omitempty"`         // 每页条数的请求参数名（page/offset）
+	PageSize     int    `bson:"page_size,omitempty" json:"page_size,omitempty"`           // 每页条数，offset 模式必填
+	Start        int    `bson:"start,omitempty" json:"start,omitempty"`                   // 起始页码或偏移量，page 模式默认 1
+	CursorField  string `bson:"cursor_field,omitempty" json:"cursor_field,omitempty"`     // cursor 模式：响应中下一页游标的字段路径
+	HasMoreField string `bson:"has_more_field,omitempty" json:"has_more_field,omitempty"` // 响应中"是否还有下一页"的字段路径，为假时停止
+	MaxPages     int    `bson:"max_pages,omitempty" json:"max_pages,omitempty"`           // 最多抓取页数，默认 10
+	Store        string `bson:"store,omitempty" json:"store,omitempty"`                   // "merge"（默认，合并为一条）| "linked"（每页一条）
+}
+
+// HTMLExtract 基于 CSS 选择器的 HTML 列表抽取规则
+type HTMLExtract struct {
+	List       string                   `bson:"list" json:"list"`                                   // 列表项选择器，如 "ul.news-list > li"
+	Title      *FieldSelector           `bson:"title,omitempty" json:"title,omitempty"`             // 标题
+	URL        *FieldSelector           `bson:"url,omitempty" json:"url,omitempty"`                 // 链接，相对地址会基于页面地址补全
+	Time       *FieldSelector           `bson:"time,omitempty" json:"time,omitempty"`               // 发布时间
+	Summary    *FieldSelector           `bson:"summary,omitempty" json:"summary,omitempty"`         // 摘要
+	Fields     map[string]FieldSelector `bson:"fields,omitempty" json:"fields,omitempty"`           // 其他自定义字段
+	TimeLayout string                   `bson:"time_layout,omitempty" json:"time_layout,omitempty"` // 时间格式（Go layout），为空时尝试常见格式
+}
+
+// FieldSelector 列表项内单个字段的抽取方式
+type FieldSelector struct {
+	Selector string `bson:"selector,omitempty" json:"selector,omitempty"` // 相对列表项的选择器，为空表示列表项本身
+	Attr     string `bson:"attr,omitempty" json:"attr,omitempty"`         // 读取的属性名，如 "href"、"datetime"；为空时取文本
 }
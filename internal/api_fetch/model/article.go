@@ -0,0 +1,22 @@
+package model
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// Article 去重后的条目（articles 集合），同一来源下按 DedupKey 唯一
+type Article struct {
+	ID        primitive.ObjectID     `bson:"_id,omitempty" json:"_id,omitempty"`
+	Source    string                 `bson:"source" json:"source"`
+	Category  string                 `bson:"category" json:"category"`
+	InfoType  string                 `bson:"info_type" json:"info_type"`
+	DedupKey  string                 `bson:"dedup_key" json:"dedup_key"`
+	Data      map[string]interface{} `bson:"data" json:"data"`             // 最近一次处理得到的条目内容
+	FirstSeen time.Time              `bson:"first_seen" json:"first_seen"` // 首次出现时间（UTC）
+	LastSeen  time.Time              `bson:"last_seen" json:"last_seen"`   // 最近出现时间（UTC）
+	SeenCount int                    `bson:"seen_count" json:"seen_count"` // 出现在多少次抓取结果中
+	FirstDate string                 `bson:"first_date" json:"first_date"` // 首次出现的日期 YYYY-MM-DD
+	LastDate  string                 `bson:"last_date" json:"last_date"`   // 最近出现的日期 YYYY-MM-DD
+	RawDocID  string                 `bson:"raw_doc_id" json:"raw_doc_id"` // 最近一次来源的原始文档ID
+}
@@ -0,0 +1,19 @@
+package model
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// APIAudit apis 配置的变更记录（api_audit 集合）
+type APIAudit struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	APIID      string             `bson:"api_id" json:"api_id"`
+	Action     string             `bson:"action" json:"action"`                       // create | replace | patch | delete | enable | disable
+	Actor      string             `bson:"actor" json:"actor"`                         // 操作人，取自认证令牌对应的名字
+	RemoteAddr string             `bson:"remote_addr" json:"remote_addr"`             // 请求来源 IP
+	Changes    []string           `bson:"changes,omitempty" json:"changes,omitempty"` // 发生变化的字段
+	Before     *APIInfo           `bson:"before,omitempty" json:"before,omitempty"`
+	After      *APIInfo           `bson:"after,omitempty" json:"after,omitempty"`
+	ChangedAt  time.Time          `bson:"changed_at" json:"changed_at"` // UTC
+}
@@ -0,0 +1,48 @@
+package model
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// FetchRun 一次抓取或后处理运行（fetch_runs 集合），定时触发与手动触发都会记录
+type FetchRun struct {
+	ID         string            `bson:"_id" json:"id"`
+	Kind       string            `bson:"kind" json:"kind"`       // fetch | process
+	Trigger    string            `bson:"trigger" json:"trigger"` // schedule | manual
+	Filter     map[string]string `bson:"filter,omitempty" json:"filter,omitempty"`
+	Status     string            `bson:"status" json:"status"` // running | succeeded | partial | failed
+	Total      int               `bson:"total" json:"total"`   // API 数（fetch）或文档数（process）
+	Succeeded  int               `bson:"succeeded" json:"succeeded"`
+	Failed     int               `bson:"failed" json:"failed"`
+	Skipped    int               `bson:"skipped,omitempty" json:"skipped,omitempty"`     // 上一次运行仍未结束而跳过的 API 数
+	Anomalies  int               `bson:"anomalies,omitempty" json:"anomalies,omitempty"` // 条目数异常的次数
+	Error      string            `bson:"error,omitempty" json:"error,omitempty"`
+	StartedAt  time.Time         `bson:"started_at" json:"started_at"` // UTC
+	FinishedAt *time.Time        `bson:"finished_at,omitempty" json:"finished_at,omitempty"`
+}
+
+// FetchAttempt 单个 API 的一次抓取尝试（fetch_attempts 集合）
+type FetchAttempt struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	RunID      string             `bson:"run_id,omitempty" json:"run_id,omitempty"`
+	APIID      string             `bson:"api_id" json:"api_id"`
+	Source     string             `bson:"source" json:"source"`
+	Category   string             `bson:"category" json:"category"`
+	InfoType   string             `bson:"info_type" json:"info_type"`
+	Attempt    int                `bson:"attempt" json:"attempt"` // 第几次尝试，1 为首次
+	Success    bool               `bson:"success" json:"success"`
+	URL        string             `bson:"url,omitempty" json:"url,omitempty"`                 // 最后一次请求的地址
+	StatusCode int                `bson:"status_code,omitempty" json:"status_code,omitempty"` // 最后一次响应的状态码
+	LatencyMS  int64              `bson:"latency_ms" json:"latency_ms"`                       // 各页 HTTP 请求耗时之和
+	BodySize   int                `bson:"body_size" json:"body_size"`                         // 各页响应体字节数之和
+	Pages      int                `bson:"pages" json:"pages"`
+	ItemCount  int                `bson:"item_count" json:"item_count"` // 提取出的条目数，无法判断时为 -1
+	Strategy   string             `bson:"strategy,omitempty" json:"strategy,omitempty"`
+	Anomaly    string             `bson:"anomaly,omitempty" json:"anomaly,omitempty"`     // 条目数异常：zero_items | item_drop
+	Unchanged  string             `bson:"unchanged,omitempty" json:"unchanged,omitempty"` // 内容未变未保存：not_modified（304）| same_hash
+	ErrorClass string             `bson:"error_class,omitempty" json:"error_class,omitempty"`
+	Error      string             `bson:"error,omitempty" json:"error,omitempty"`
+	StartedAt  time.Time          `bson:"started_at" json:"started_at"` // UTC
+	FinishedAt time.Time          `bson:"finished_at" json:"finished_at"`
+}
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// FetchState 某个 API 上次保存的响应状态（fetch_state 集合），用于条件请求与内容去重
+type FetchState struct {
+	ID           string    `bson:"_id" json:"id"` // API ID
+	ETag         string    `bson:"etag,omitempty" json:"etag,omitempty"`
+	LastModified string    `bson:"last_modified,omitempty" json:"last_modified,omitempty"`
+	ContentHash  string    `bson:"content_hash" json:"content_hash"` // 提取数据的 SHA-256
+	Date         string    `bson:"date" json:"date"`                 // 上次保存到的分表日期（Asia/Shanghai）
+	StoredAt     time.Time `bson:"stored_at" json:"stored_at"`       // 上次保存时间（UTC）
+	CheckedAt    time.Time `bson:"checked_at" json:"checked_at"`     // 上次确认内容未变的时间（UTC）
+}
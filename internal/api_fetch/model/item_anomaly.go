@@ -0,0 +1,34 @@
+package model
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// ItemBaseline 某个 API 或后处理函数最近若干次的条目数（item_baselines 集合），作为滚动基线
+type ItemBaseline struct {
+	ID        string    `bson:"_id" json:"id"`    // kind:key
+	Kind      string    `bson:"kind" json:"kind"` // fetch | process
+	Key       string    `bson:"key" json:"key"`   // API ID 或后处理函数键
+	Source    string    `bson:"source" json:"source"`
+	Category  string    `bson:"category" json:"category"`
+	InfoType  string    `bson:"info_type" json:"info_type"`
+	Counts    []int     `bson:"counts" json:"counts"` // 按时间顺序，最新的在最后
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// ItemAnomaly 一次条目数异常（item_anomalies 集合）
+type ItemAnomaly struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Kind        string             `bson:"kind" json:"kind"` // fetch | process
+	Key         string             `bson:"key" json:"key"`
+	RunID       string             `bson:"run_id,omitempty" json:"run_id,omitempty"`
+	Source      string             `bson:"source" json:"source"`
+	Category    string             `bson:"category" json:"category"`
+	InfoType    string             `bson:"info_type" json:"info_type"`
+	Reason      string             `bson:"reason" json:"reason"` // zero_items | item_drop
+	Count       int                `bson:"count" json:"count"`
+	Baseline    float64            `bson:"baseline" json:"baseline"`         // 最近若干次条目数的中位数
+	DropPercent float64            `bson:"drop_percent" json:"drop_percent"` // 相对基线下降的百分比
+	DetectedAt  time.Time          `bson:"detected_at" json:"detected_at"`   // UTC
+}
@@ -11,10 +11,22 @@ type CrawlResult struct {
 	Date      string             `bson:"date" json:"date"` // YYYY-MM-DD（按 Asia/Shanghai 计算）
 	Source    string             `bson:"source" json:"source"`
 	Category  string             `bson:"category" json:"category"`
-	InfoType  string             `bson:"info_type" json:"info_type"`  // 信息类型
-	Data      bson.M             `bson:"data" json:"data"`            // 原始/解析后的内容
-	Processed bool               `bson:"processed" json:"processed"`  // 是否已处理
-	CreatedAt time.Time          `bson:"createdAt" json:"created_at"` // UTC
+	InfoType  string             `bson:"info_type" json:"info_type"`                   // 信息类型
+	Data      bson.M             `bson:"data" json:"data"`                             // 原始/解析后的内容
+	Processed bool               `bson:"processed" json:"processed"`                   // 是否已处理
+	CreatedAt time.Time          `bson:"createdAt" json:"created_at"`                  // UTC
+	Encoding  *DetectedCharset   `bson:"encoding,omitempty" json:"encoding,omitempty"` // 响应原始编码，已统一转为 UTF-8 存储
+
+	// 分页以 linked 方式存储时，同一次抓取的各页共享 PageGroup
+	PageGroup string `bson:"page_group,omitempty" json:"page_group,omitempty"`
+	Page      int    `bson:"page,omitempty" json:"page,omitempty"`             // 页序号，从 1 开始
+	PageCount int    `bson:"page_count,omitempty" json:"page_count,omitempty"` // 本次抓取的总页数
+}
+
+// DetectedCharset 检测到的响应编码
+type DetectedCharset struct {
+	Name   string `bson:"name" json:"name"`     // 规范化的编码名，如 utf-8、gbk、big5
+	Source string `bson:"source" json:"source"` // 判断依据：override | bom | content-type | document | guess | default
 }
 
 // ProcessedData 处理后的数据
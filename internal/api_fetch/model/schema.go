@@ -0,0 +1,46 @@
+package model
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// SchemaFingerprint 某个 API 提取数据的结构指纹（schema_fingerprints 集合），由历次响应累积学习
+type SchemaFingerprint struct {
+	ID        string        `bson:"_id" json:"id"` // API ID
+	Source    string        `bson:"source" json:"source"`
+	Category  string        `bson:"category" json:"category"`
+	InfoType  string        `bson:"info_type" json:"info_type"`
+	Samples   int           `bson:"samples" json:"samples"` // 已学习的响应数
+	Fields    []SchemaField `bson:"fields" json:"fields"`
+	LearnedAt time.Time     `bson:"learned_at" json:"learned_at"` // 首次学习时间（UTC）
+	UpdatedAt time.Time     `bson:"updated_at" json:"updated_at"`
+}
+
+// SchemaField 单个字段路径，如 hotNews[*].contId
+type SchemaField struct {
+	Path  string   `bson:"path" json:"path"`
+	Types []string `bson:"types" json:"types"` // 出现过的值类型
+	Hits  int      `bson:"hits" json:"hits"`   // 出现在多少次响应中，等于 Samples 表示每次都有
+}
+
+// SchemaDrift 一次结构变化（schema_drifts 集合）
+type SchemaDrift struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	APIID      string             `bson:"api_id" json:"api_id"`
+	RunID      string             `bson:"run_id,omitempty" json:"run_id,omitempty"`
+	Source     string             `bson:"source" json:"source"`
+	Category   string             `bson:"category" json:"category"`
+	InfoType   string             `bson:"info_type" json:"info_type"`
+	Added      []string           `bson:"added,omitempty" json:"added,omitempty"`     // 新出现的字段
+	Removed    []string           `bson:"removed,omitempty" json:"removed,omitempty"` // 以往每次都有、本次缺失的字段
+	Retyped    []RetypedField     `bson:"retyped,omitempty" json:"retyped,omitempty"` // 出现了新值类型的字段
+	DetectedAt time.Time          `bson:"detected_at" json:"detected_at"`             // UTC
+}
+
+// RetypedField 字段值类型变化
+type RetypedField struct {
+	Path string   `bson:"path" json:"path"`
+	From []string `bson:"from" json:"from"`
+	To   string   `bson:"to" json:"to"`
+}
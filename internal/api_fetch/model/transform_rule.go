@@ -0,0 +1,64 @@
+package model
+
+import "time"
+
+// TransformRule 声明式后处理规则，存储在 transform_rules 集合中
+// 按 source_category_infotype 注册为处理函数，新增来源无需写 Go 代码和发版
+type TransformRule struct {
+	ID       string `bson:"_id,omitempty" json:"id"`
+	Source   string `bson:"source" json:"source"`
+	Category string `bson:"category" json:"category"`
+	InfoType string `bson:"info_type" json:"info_type"`
+	Enabled  bool   `bson:"enabled" json:"enabled"`
+
+	// Lists 要处理的子列表及其标签；为空时处理 data.items
+	Lists []ListRule `bson:"lists,omitempty" json:"lists,omitempty"`
+	// LabelField 子列表标签写入条目的字段名，如 "seriesType"
+	LabelField string `bson:"label_field,omitempty" json:"label_field,omitempty"`
+	// Filters 条目过滤条件，语法同 APIInfo.Required，如 {"contType": {"$in": [0, 1, 9, 15]}}
+	Filters map[string]any `bson:"filters,omitempty" json:"filters,omitempty"`
+	// IDFields 按顺序取第一个非空值作为条目 ID，如 ["contId", "originalContId"]；都为空的条目会被丢弃
+	IDFields []string `bson:"id_fields,omitempty" json:"id_fields,omitempty"`
+	// IDTarget 条目 ID 写入的字段名，默认 "articleID"
+	IDTarget string `bson:"id_target,omitempty" json:"id_target,omitempty"`
+	// Fields 字段映射
+	Fields []FieldMapping `bson:"fields,omitempty" json:"fields,omitempty"`
+	// URLTemplate 链接模板（text/template），以转换后的条目为数据，如 "https://www.thepaper.cn/detail/{{.articleID}}"
+	URLTemplate string `bson:"url_template,omitempty" json:"url_template,omitempty"`
+	// URLTarget 链接写入的字段名，默认 "origin_url"
+	URLTarget string `bson:"url_target,omitempty" json:"url_target,omitempty"`
+	// Constants 固定写入每个条目的字段
+	Constants map[string]any `bson:"constants,omitempty" json:"constants,omitempty"`
+	// OutputField ProcessedData.Data 中条目数组的字段名，默认 "articles"
+	OutputField string `bson:"output_field,omitempty" json:"output_field,omitempty"`
+	// DedupFields 条目去重键字段，默认 ["articleID"]，取不到时退化为 url+title 的哈希
+	DedupFields []string `bson:"dedup_fields,omitempty" json:"dedup_fields,omitempty"`
+
+	// Script 脚本转换，声明式字段表达不了的逻辑用脚本实现；配置后忽略上面的声明式字段
+	Script *ScriptTransform `bson:"script,omitempty" json:"script,omitempty"`
+
+	UpdatedAt time.Time `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+}
+
+// ScriptTransform JavaScript 转换脚本
+// 脚本需定义 function transform(data, doc)：data 为 CrawlResult.Data，doc 为 {id, source, category, info_type, date}，
+// 返回值作为 ProcessedData.Data，必须是对象
+type ScriptTransform struct {
+	Code        string `bson:"code" json:"code"`
+	TimeoutMS   int    `bson:"timeout_ms,omitempty" json:"timeout_ms,omitempty"`       // 单次执行超时，默认 2000ms
+	MaxMemoryMB int    `bson:"max_memory_mb,omitempty" json:"max_memory_mb,omitempty"` // 单次执行的堆增长上限，默认 64MB；按进程级堆统计，并发执行时为近似值
+}
+
+// ListRule 一个待处理的子列表
+type ListRule struct {
+	Path  string `bson:"path" json:"path"`                       // CrawlResult.Data 中的路径，如 "hotNews"
+	Label string `bson:"label,omitempty" json:"label,omitempty"` // 写入 LabelField 的标签，如 "热点新闻"
+}
+
+// FieldMapping 单个字段映射
+type FieldMapping struct {
+	Target   string   `bson:"target" json:"target"`                         // 输出字段名
+	From     []string `bson:"from" json:"from"`                             // 源字段路径，按顺序取第一个非空值
+	Default  any      `bson:"default,omitempty" json:"default,omitempty"`   // 都为空时的默认值，未设置则不写该字段
+	Required bool     `bson:"required,omitempty" json:"required,omitempty"` // 为 true 时取不到值的条目会被丢弃
+}
@@ -0,0 +1,210 @@
+package processor
+
+import (
+	"api-fetch/internal/api_fetch/alert"
+	"api-fetch/internal/api_fetch/helper"
+	"api-fetch/internal/api_fetch/metrics"
+	"api-fetch/internal/api_fetch/model"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+// 条目数统计的类型
+const (
+	ItemKindFetch   = "fetch"   // extractData 提取出的条目数
+	ItemKindProcess = "process" // 后处理输出的条目数（如 articles 的长度）
+)
+
+// 条目数异常原因
+const (
+	AnomalyZeroItems = "zero_items" // 基线不为零时本次为零
+	AnomalyItemDrop  = "item_drop"  // 低于基线超过 MaxDropPercent
+)
+
+// 默认参数
+const (
+	defaultAnomalyWindow     = 20
+	defaultAnomalyMinSamples = 5
+	defaultAnomalyMaxDrop    = 50
+)
+
+// AnomalyConfig 条目数异常检测配置（config.yaml 中的 anomaly 段）
+type AnomalyConfig struct {
+	Disabled       bool    `yaml:"disabled"`
+	Window         int     `yaml:"window"`           // 基线取最近多少次的条目数，默认 20
+	MinSamples     int     `yaml:"min_samples"`      // 样本数达到多少后才开始判断，默认 5
+	MaxDropPercent float64 `yaml:"max_drop_percent"` // 低于基线中位数多少百分比视为异常，默认 50
+}
+
+func (c AnomalyConfig) withDefaults() AnomalyConfig {
+	if c.Window <= 0 {
+		c.Window = defaultAnomalyWindow
+	}
+	if c.MinSamples <= 0 {
+		c.MinSamples = defaultAnomalyMinSamples
+	}
+	if c.MinSamples > c.Window {
+		c.MinSamples = c.Window
+	}
+	if c.MaxDropPercent <= 0 || c.MaxDropPercent > 100 {
+		c.MaxDropPercent = defaultAnomalyMaxDrop
+	}
+	return c
+}
+
+// evaluate 将本次条目数与历史样本比较；样本不足或基线为零时不判断
+func (c AnomalyConfig) evaluate(count int, history []int) (reason string, baseline, drop float64) {
+	if len(history) < c.MinSamples {
+		return "", 0, 0
+	}
+	baseline = median(history)
+	if baseline <= 0 {
+		return "", baseline, 0
+	}
+	drop = (baseline - float64(count)) / baseline * 100
+	switch {
+	case count == 0:
+		return AnomalyZeroItems, baseline, drop
+	case drop > c.MaxDropPercent:
+		return AnomalyItemDrop, baseline, drop
+	default:
+		return "", baseline, drop
+	}
+}
+
+func median(values []int) float64 {
+	sorted := append([]int(nil), values...)
+	sort.Ints(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return float64(sorted[n/2])
+	}
+	return float64(sorted[n/2-1]+sorted[n/2]) / 2
+}
+
+// itemSample 一次待检查的条目数
+type itemSample struct {
+	kind     string
+	key      string
+	runID    string
+	source   string
+	category string
+	infoType string
+	count    int
+}
+
+// anomalyDetector 维护条目数滚动基线并记录、告警异常，Processor 与 DataProcessor 共用
+type anomalyDetector struct {
+	log    *zap.Logger
+	stores *helper.Stores
+	alerts *alert.Manager
+	cfg    AnomalyConfig
+}
+
+// check 与基线比较后把本次条目数追加到基线；异常时写入 item_anomalies 并告警。
+// 失败只记日志，返回 nil
+func (d anomalyDetector) check(ctx context.Context, s itemSample) *model.ItemAnomaly {
+	if d.cfg.Disabled || d.stores == nil || d.stores.ItemBaselines == nil || s.count < 0 {
+		return nil
+	}
+	cfg := d.cfg.withDefaults()
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), recordAttemptTimeout)
+	defer cancel()
+
+	log := d.log.With(
+		zap.String("kind", s.kind),
+		zap.String("source", s.source),
+		zap.String("category", s.category),
+	)
+	id := s.kind + ":" + s.key
+
+	var base model.ItemBaseline
+	err := d.stores.ItemBaselines.FindOne(ctx, bson.M{"_id": id}).Decode(&base)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		log.Warn("Failed to load item baseline", zap.Error(err))
+		return nil
+	}
+	reason, baseline, drop := cfg.evaluate(s.count, base.Counts)
+
+	// 异常值同样计入基线：上游长期下降时基线会随之调整，不会一直告警
+	update := bson.M{
+		"$set": bson.M{
+			"kind":       s.kind,
+			"key":        s.key,
+			"source":     s.source,
+			"category":   s.category,
+			"info_type":  s.infoType,
+			"updated_at": time.Now().UTC(),
+		},
+		"$push": bson.M{"counts": bson.M{"$each": []int{s.count}, "$slice": -cfg.Window}},
+	}
+	if _, err := d.stores.ItemBaselines.UpdateOne(ctx, bson.M{"_id": id}, update, options.Update().SetUpsert(true)); err != nil {
+		log.Warn("Failed to update item baseline", zap.Error(err))
+	}
+	if reason == "" {
+		return nil
+	}
+
+	anomaly := &model.ItemAnomaly{
+		Kind:        s.kind,
+		Key:         s.key,
+		RunID:       s.runID,
+		Source:      s.source,
+		Category:    s.category,
+		InfoType:    s.infoType,
+		Reason:      reason,
+		Count:       s.count,
+		Baseline:    baseline,
+		DropPercent: drop,
+		DetectedAt:  time.Now().UTC(),
+	}
+	if _, err := d.stores.ItemAnomalies.InsertOne(ctx, anomaly); err != nil {
+		log.Warn("Failed to record item anomaly", zap.Error(err))
+	}
+	metrics.ItemAnomaly(s.source, s.category, s.infoType, s.kind, reason)
+	log.Warn("Item count anomaly detected",
+		zap.String("reason", reason),
+		zap.Int("count", s.count),
+		zap.Float64("baseline", baseline),
+	)
+
+	apiID := ""
+	if s.kind == ItemKindFetch {
+		apiID = s.key
+	}
+	d.alerts.Notify(alert.Alert{
+		Event:    alert.EventItemAnomaly,
+		Source:   s.source,
+		Category: s.category,
+		InfoType: s.infoType,
+		APIID:    apiID,
+		Title:    fmt.Sprintf("%s/%s returned %d items, baseline %.0f", s.source, s.category, s.count, baseline),
+		Message:  fmt.Sprintf("The %s item count dropped %.0f%% below the median of the last %d runs.", s.kind, drop, len(base.Counts)),
+		Fields: map[string]string{
+			"kind":   s.kind,
+			"reason": reason,
+			"count":  strconv.Itoa(s.count),
+			"run_id": s.runID,
+		},
+	})
+	return anomaly
+}
+
+// anomalies 抓取结果的条目数检测器
+func (p *Processor) anomalies() anomalyDetector {
+	return anomalyDetector{log: p.Log, stores: p.Stores, alerts: p.Alerts, cfg: p.Anomaly}
+}
+
+// anomalies 后处理结果的条目数检测器
+func (dp *DataProcessor) anomalies() anomalyDetector {
+	return anomalyDetector{log: dp.Log, stores: dp.Stores, alerts: dp.Alerts, cfg: dp.Anomaly}
+}
@@ -1,11 +1,14 @@
 package processor
 
 import (
+	"api-fetch/internal/api_fetch/alert"
 	"api-fetch/internal/api_fetch/helper"
+	"api-fetch/internal/api_fetch/metrics"
 	"api-fetch/internal/api_fetch/model"
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -15,6 +18,7 @@ import (
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.uber.org/zap"
 )
@@ -23,6 +27,14 @@ type Processor struct {
 	Log        *zap.Logger
 	Stores     *helper.Stores
 	HTTPClient *http.Client
+	Alerts     *alert.Manager // 可为空
+	Anomaly    AnomalyConfig
+	Limiter    *FetchLimiter   // 全局并发与按 host 限速，可为空
+	Breakers   *Breakers       // 按 host 或 API 熔断，可为空
+	Auth       *Authenticators // 请求认证的令牌缓存与密钥轮换状态，为空时每次请求重新认证
+
+	// OnItemAnomaly 抓取条目数异常时调用，用于计入运行记录，可为空
+	OnItemAnomaly func(a *model.ItemAnomaly)
 }
 
 // NewProcessor 创建新的数据处理器
@@ -31,53 +43,98 @@ func NewProcessor(log *zap.Logger, stores *helper.Stores, httpClient *http.Clien
 		Log:        log,
 		Stores:     stores,
 		HTTPClient: httpClient,
+		Auth:       NewAuthenticators(httpClient),
 	}
 }
 
-// ProcessAPIWithRetry 处理单个API，包含异步重试机制
-func (p *Processor) ProcessAPIWithRetry(ctx context.Context, api *model.APIInfo, contentColl *mongo.Collection, now time.Time, retryWg *sync.WaitGroup) {
+// ProcessAPIWithRetry 处理单个API，失败时按 API 的重试策略异步重试
+// 每次尝试记入 fetch_attempts 并关联 runID；done 在最终结果确定时调用一次（首次成功、重试成功或放弃），
+// 成功时 err 为 nil，否则为最后一次尝试的错误；可为 nil
+func (p *Processor) ProcessAPIWithRetry(ctx context.Context, api *model.APIInfo, contentColl *mongo.Collection, now time.Time, retryWg *sync.WaitGroup, runID string, done func(err error)) {
+	if done == nil {
+		done = func(error) {}
+	}
+	policy, err := newRetryPolicy(api.Retry)
+	if err != nil {
+		p.Log.Warn("Invalid retry policy, using default",
+			zap.String("source", api.Source),
+			zap.String("category", api.Category),
+			zap.Error(err),
+		)
+		policy, _ = newRetryPolicy(nil)
+	}
+
 	// 第一次尝试同步执行
-	success := p.fetchAndSave(ctx, api, contentColl, now, 1)
-	if success {
+	err = p.fetchAndSave(ctx, api, contentColl, now, 1, runID)
+	if err == nil {
+		done(nil)
+		return
+	}
+	if !p.shouldRetry(api, policy, 1, err) {
+		done(err)
 		return
 	}
 
-	// 第一次失败，启动异步重试goroutine
+	// 第一次失败且可重试，启动异步重试goroutine
 	retryWg.Add(1)
 	go func() {
 		defer retryWg.Done()
-		p.asyncRetryLoop(ctx, api, contentColl, now)
+		done(p.asyncRetryLoop(ctx, api, contentColl, now, runID, policy, err))
 	}()
 }
 
-// calculateRetryDelay 计算重试延迟时间：15s * 2^(n-1)
-func (p *Processor) calculateRetryDelay(retryCount int) time.Duration {
-	if retryCount <= 0 {
-		return 15 * time.Second
-	}
-	// 15s * 2^(n-1)
-	delay := 15 * time.Second
-	for i := 1; i < retryCount; i++ {
-		delay *= 2
+// shouldRetry 第 attempt 次尝试失败后是否继续重试；不重试时记录原因，重试用尽时告警
+func (p *Processor) shouldRetry(api *model.APIInfo, policy retryPolicy, attempt int, err error) bool {
+	class := ErrorClass(err)
+	_, retryAfter := responseInfo(err)
+	log := p.Log.With(
+		zap.String("source", api.Source),
+		zap.String("category", api.Category),
+		zap.Int("attempt", attempt),
+		zap.String("errorClass", class),
+	)
+	switch {
+	case class == ErrClassCanceled:
+		return false
+	case !policy.retryable(err):
+		log.Warn("Fetch failed with non-retryable error, giving up", zap.Error(err))
+		return false
+	case retryAfter > maxRetryAfter:
+		log.Warn("Retry-After exceeds limit, giving up", zap.Duration("retryAfter", retryAfter))
+		return false
+	case attempt < policy.maxAttempts:
+		return true
 	}
-	return delay
-}
 
-// asyncRetryLoop 异步重试循环
-func (p *Processor) asyncRetryLoop(ctx context.Context, api *model.APIInfo, contentColl *mongo.Collection, now time.Time) {
-	const maxRetries = 5
+	// 达到最大尝试次数
+	log.Error("Async retry max attempts exceeded, giving up", zap.Int("maxAttempts", policy.maxAttempts))
+	metrics.RetriesExhausted(api.Source, api.Category, api.InfoType)
+	p.Alerts.Notify(alert.Alert{
+		Event:    alert.EventRetryExhausted,
+		Source:   api.Source,
+		Category: api.Category,
+		InfoType: api.InfoType,
+		APIID:    api.ID,
+		Title:    fmt.Sprintf("%s/%s fetch failed after %d attempts", api.Source, api.Category, attempt),
+		Message:  "All retries were exhausted, no data was saved for this run.",
+		Fields:   map[string]string{"url": api.URL, "error_class": class, "error": err.Error()},
+	})
+	return false
+}
 
-	for attempt := 2; attempt <= maxRetries; attempt++ {
-		// 计算重试延迟：15s * 2^(n-1)
-		retryDelay := p.calculateRetryDelay(attempt - 1) // attempt-1 因为这是第2次开始
+// asyncRetryLoop 异步重试循环，lastErr 为上一次尝试的错误，返回最终的错误，成功时为 nil
+func (p *Processor) asyncRetryLoop(ctx context.Context, api *model.APIInfo, contentColl *mongo.Collection, now time.Time, runID string, policy retryPolicy, lastErr error) error {
+	for attempt := 2; attempt <= policy.maxAttempts; attempt++ {
+		retryDelay := policy.delay(attempt-1, lastErr)
 
 		p.Log.Info("Async retry scheduled",
 			zap.String("source", api.Source),
 			zap.String("category", api.Category),
 			zap.Int("attempt", attempt),
-			zap.Int("maxRetries", maxRetries),
+			zap.Int("maxAttempts", policy.maxAttempts),
 			zap.Duration("delay", retryDelay),
 		)
+		metrics.RetryScheduled(api.Source, api.Category, api.InfoType)
 
 		// 等待重试延迟
 		timer := time.NewTimer(retryDelay)
@@ -89,57 +146,256 @@ func (p *Processor) asyncRetryLoop(ctx context.Context, api *model.APIInfo, cont
 				zap.String("category", api.Category),
 				zap.Int("attempt", attempt),
 			)
-			return
+			return classify(ErrClassCanceled, ctx.Err())
 		case <-timer.C:
-			// 执行重试
-			success := p.fetchAndSave(ctx, api, contentColl, now, attempt)
-			if success {
-				p.Log.Info("Async retry succeeded",
-					zap.String("source", api.Source),
-					zap.String("category", api.Category),
-					zap.Int("attempt", attempt),
-				)
-				return // 成功，退出重试循环
-			}
+		}
+
+		// 执行重试
+		lastErr = p.fetchAndSave(ctx, api, contentColl, now, attempt, runID)
+		if lastErr == nil {
+			p.Log.Info("Async retry succeeded",
+				zap.String("source", api.Source),
+				zap.String("category", api.Category),
+				zap.Int("attempt", attempt),
+			)
+			return nil // 成功，退出重试循环
+		}
+		if !p.shouldRetry(api, policy, attempt, lastErr) {
+			return lastErr
 		}
 	}
+	return lastErr
+}
 
-	// 达到最大重试次数
-	p.Log.Error("Async retry max attempts exceeded, giving up",
-		zap.String("source", api.Source),
-		zap.String("category", api.Category),
-		zap.Int("maxRetries", maxRetries),
-	)
+// fetchedPage 单页抓取并提取后的数据
+type fetchedPage struct {
+	data     bson.M
+	strategy string
+	charset  model.DetectedCharset // 响应原始编码
 }
 
-// fetchAndSave 获取API数据并保存到数据库
-func (p *Processor) fetchAndSave(ctx context.Context, api *model.APIInfo, contentColl *mongo.Collection, now time.Time, attempt int) bool {
-	// 1. 构建HTTP请求
-	req, err := p.buildHTTPRequest(ctx, api, attempt)
-	if err != nil {
-		return false
+// fetchAndSave 获取API数据并保存到数据库，结果记入 fetch_attempts，返回带分类的错误
+func (p *Processor) fetchAndSave(ctx context.Context, api *model.APIInfo, contentColl *mongo.Collection, now time.Time, attempt int, runID string) error {
+	// 熔断中直接跳过，不占用并发槽位
+	if !p.Breakers.allow(api, time.Now()) {
+		err := classify(ErrClassCircuit, errCircuitOpen)
+		rec := newAttemptStats().attempt(api, runID, attempt, err)
+		metrics.FetchAttempt(api.Source, api.Category, api.InfoType, false, rec.ErrorClass)
+		p.recordAttempt(ctx, rec)
+		return err
 	}
 
-	// 2. 执行HTTP请求
-	resp, err := p.HTTPClient.Do(req)
+	// 等待并发槽位的时间不计入本次尝试
+	slot, err := p.Limiter.acquire(ctx)
 	if err != nil {
-		p.Log.Error("Failed to fetch API",
-			zap.String("url", api.URL),
+		err = classify(ErrClassCanceled, err)
+		p.Breakers.report(api, err, time.Now())
+		p.recordAttempt(ctx, newAttemptStats().attempt(api, runID, attempt, err))
+		return err
+	}
+	defer slot.release()
+
+	stats := newAttemptStats()
+	stats.slot = slot
+	stats.cond = p.loadConditional(ctx, api, now)
+	pages, err := p.fetchPages(ctx, api, now, attempt, stats)
+	unchanged := ""
+	if errors.Is(err, errNotModified) {
+		err, unchanged = nil, UnchangedNotModified
+	}
+	p.Breakers.report(api, err, time.Now())
+	if err == nil && unchanged == "" {
+		stats.addPages(pages)
+		// 无法计算哈希时按内容已变化处理
+		hash, hashErr := contentHash(pages)
+		switch {
+		case hashErr == nil && stats.cond.unchanged(hash):
+			unchanged = UnchangedSameHash
+		case !p.saveToDatabase(ctx, pages, api, contentColl, now, attempt):
+			err = classify(ErrClassStorage, fmt.Errorf("failed to insert document"))
+		default:
+			p.saveFetchState(ctx, api, stats.cond, hash)
+			// 各页结构相同，以首页为准比较结构变化
+			p.checkSchema(ctx, api, pages[0].data, runID)
+		}
+	}
+
+	rec := stats.attempt(api, runID, attempt, err)
+	rec.Unchanged = unchanged
+	switch {
+	case unchanged != "":
+		// 内容未变不保存，也就不会被重复后处理
+		p.touchFetchState(ctx, stats.cond, unchanged)
+		metrics.FetchUnchanged(api.Source, api.Category, api.InfoType, unchanged)
+		p.Log.Debug("API content unchanged, skipped storing",
+			zap.String("source", api.Source),
+			zap.String("category", api.Category),
+			zap.String("reason", unchanged),
+		)
+	case err == nil:
+		if a := p.checkItemCount(ctx, api, stats.items, runID); a != nil {
+			rec.Anomaly = a.Reason
+		}
+	}
+	metrics.FetchAttempt(api.Source, api.Category, api.InfoType, rec.Success, rec.ErrorClass)
+	p.recordAttempt(ctx, rec)
+	return err
+}
+
+// checkItemCount 将本次提取的条目数与该 API 的滚动基线比较，无法判断条目数时跳过
+func (p *Processor) checkItemCount(ctx context.Context, api *model.APIInfo, items int, runID string) *model.ItemAnomaly {
+	key := api.ID
+	if key == "" {
+		key = processorKey(api.Source, api.Category, api.InfoType)
+	}
+	a := p.anomalies().check(ctx, itemSample{
+		kind:     ItemKindFetch,
+		key:      key,
+		runID:    runID,
+		source:   api.Source,
+		category: api.Category,
+		infoType: api.InfoType,
+		count:    items,
+	})
+	if a != nil && p.OnItemAnomaly != nil {
+		p.OnItemAnomaly(a)
+	}
+	return a
+}
+
+// fetchPages 按分页配置依次抓取所有页，任一页失败则整体失败；now 为本次运行时间，用于渲染请求模板
+func (p *Processor) fetchPages(ctx context.Context, api *model.APIInfo, now time.Time, attempt int, stats *attemptStats) ([]fetchedPage, error) {
+	if err := validatePagination(api.Pagination); err != nil {
+		p.Log.Error("Invalid pagination config",
 			zap.String("source", api.Source),
 			zap.String("category", api.Category),
-			zap.Int("attempt", attempt),
 			zap.Error(err),
 		)
-		return false // 网络错误，触发重试
+		return nil, classify(ErrClassConfig, err)
+	}
+
+	pg := newPager(api.Pagination)
+	var pages []fetchedPage
+	for pageReq := pg.first(); pageReq != nil; {
+		page, resp, err := p.fetchPage(ctx, api, now, attempt, pageReq, stats)
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, *page)
+		pageReq = pg.next(pageReq, resp)
+	}
+
+	if len(pages) > 1 {
+		p.Log.Debug("Fetched paginated API",
+			zap.String("source", api.Source),
+			zap.String("category", api.Category),
+			zap.Int("attempt", attempt),
+			zap.Int("pages", len(pages)),
+		)
+	}
+	return pages, nil
+}
+
+// fetchPage 抓取单页：构建请求、执行、解析校验并提取数据，请求耗时等记入 stats
+func (p *Processor) fetchPage(ctx context.Context, api *model.APIInfo, now time.Time, attempt int, pageReq *pageRequest, stats *attemptStats) (*fetchedPage, *pageResponse, error) {
+	var (
+		req            *http.Request
+		resp           *http.Response
+		sentValidators bool
+		started        time.Time
+		err            error
+	)
+	for resend := 0; ; resend++ {
+		// 1. 构建HTTP请求；记录的地址不含认证信息，模板中 secret 取到的值替换为 ***
+		data := newTemplateData(api, now, attempt, pageReq)
+		req, err = p.buildHTTPRequest(ctx, api, pageReq, data)
+		if err != nil {
+			return nil, nil, classify(ErrClassConfig, err)
+		}
+		stats.url = data.secrets.mask(req.URL.String())
+
+		sentValidators = stats.cond.apply(req, api, pageReq)
+		if err := p.applyAuth(ctx, api, req, data); err != nil {
+			p.Log.Error("Failed to authenticate request",
+				zap.String("source", api.Source),
+				zap.String("category", api.Category),
+				zap.Int("attempt", attempt),
+				zap.Error(err),
+			)
+			return nil, nil, classify(ErrClassConfig, err)
+		}
+
+		// 2. 按 host 限速后执行HTTP请求，等待期间让出全局槽位
+		if err := p.Limiter.waitHost(ctx, req.URL.Hostname(), stats.slot); err != nil {
+			return nil, nil, classify(ErrClassCanceled, err)
+		}
+		started = time.Now()
+		resp, err = p.HTTPClient.Do(req)
+		if err != nil {
+			stats.latency += time.Since(started)
+			// 不在错误信息中暴露 query 中的密钥
+			var ue *url.Error
+			if errors.As(err, &ue) {
+				ue.URL = stats.url
+			}
+			p.Log.Error("Failed to fetch API",
+				zap.String("url", stats.url),
+				zap.String("source", api.Source),
+				zap.String("category", api.Category),
+				zap.Int("attempt", attempt),
+				zap.Error(err),
+			)
+			return nil, nil, classify(ErrClassNetwork, err) // 网络错误，触发重试
+		}
+		if !p.authRejected(api, resp, resend) {
+			break
+		}
+
+		// 凭据被拒绝且已更换（令牌失效、密钥配额用尽），丢弃响应立即重发
+		stats.latency += time.Since(started)
+		p.Log.Warn("Upstream rejected credentials, resending",
+			zap.String("source", api.Source),
+			zap.String("category", api.Category),
+			zap.Int("attempt", attempt),
+			zap.Int("status", resp.StatusCode),
+		)
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
 	}
+	stats.statusCode = resp.StatusCode
 	defer func(Body io.ReadCloser) {
 		if err := Body.Close(); err != nil {
 			p.Log.Warn("Failed to close response body", zap.Error(err))
 		}
 	}(resp.Body)
 
+	// 内容未变，不再读取与解析；304 响应也可能带有新的校验值
+	stats.cond.capture(resp, pageReq)
+	if sentValidators && resp.StatusCode == http.StatusNotModified {
+		stats.latency += time.Since(started)
+		return nil, nil, errNotModified
+	}
+
+	// 限流或服务不可用时响应体不是有效数据，直接按状态码失败，由重试策略按 Retry-After 等待
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		stats.latency += time.Since(started)
+		err := withResponse(ErrClassHTTPStatus, fmt.Errorf("upstream responded %s", resp.Status), resp)
+		p.Log.Warn("API rate limited or unavailable",
+			zap.String("url", stats.url),
+			zap.String("source", api.Source),
+			zap.String("category", api.Category),
+			zap.Int("attempt", attempt),
+			zap.String("retryAfter", resp.Header.Get("Retry-After")),
+		)
+		return nil, nil, err
+	}
+
 	// 3. 读取响应体
 	body, err := io.ReadAll(resp.Body)
+	latency := time.Since(started)
+	stats.latency += latency
+	stats.bodySize += len(body)
+	metrics.FetchHTTP(api.Source, api.Category, api.InfoType, latency, len(body))
 	if err != nil {
 		p.Log.Error("Failed to read response body",
 			zap.String("source", api.Source),
@@ -147,51 +403,114 @@ func (p *Processor) fetchAndSave(ctx context.Context, api *model.APIInfo, conten
 			zap.Int("attempt", attempt),
 			zap.Error(err),
 		)
-		return false
+		return nil, nil, classify(ErrClassNetwork, err)
+	}
+
+	// 4. 检测编码并统一转为 UTF-8
+	body, charset, err := decodeBody(body, resp.Header.Get("Content-Type"), api)
+	if err != nil {
+		p.Log.Warn("Failed to decode response charset",
+			zap.String("source", api.Source),
+			zap.String("category", api.Category),
+			zap.Int("attempt", attempt),
+			zap.Error(err),
+		)
+		return nil, nil, classify(ErrClassDecode, err)
 	}
 
 	p.Log.Debug("Fetched API response",
 		zap.String("source", api.Source),
 		zap.String("category", api.Category),
 		zap.Int("attempt", attempt),
+		zap.Int("page", pageReq.index+1),
 		zap.Int("bodySize", len(body)),
+		zap.String("charset", charset.Name),
+		zap.String("charsetSource", charset.Source),
 	)
 
-	// 4. 解析和验证JSON
-	parsedObj, err := p.parseAndValidateJSON(body, api, attempt)
+	// 5. 按响应格式解析并校验
+	parsedObj, err := p.parseAndValidate(body, api, attempt, req.URL)
 	if err != nil {
-		return false
+		return nil, nil, withResponse(statusClass(resp.StatusCode, ErrClassParse), err, resp)
 	}
 
-	// 5. 提取数据
+	// 6. 提取数据
 	data, extractionStrategy, err := p.extractData(parsedObj, api, attempt)
 	if err != nil {
-		return false
+		return nil, nil, withResponse(statusClass(resp.StatusCode, ErrClassExtract), err, resp)
 	}
 
-	// 6. 保存到数据库
-	return p.saveToDatabase(ctx, data, api, contentColl, now, attempt, extractionStrategy)
+	pageResp := &pageResponse{
+		parsed: parsedObj,
+		header: resp.Header,
+		url:    req.URL,
+		items:  countItems(data),
+		empty:  emptyPage(data),
+	}
+	return &fetchedPage{data: data, strategy: extractionStrategy, charset: charset}, pageResp, nil
 }
 
-// buildHTTPRequest 构建HTTP请求
-func (p *Processor) buildHTTPRequest(ctx context.Context, api *model.APIInfo, attempt int) (*http.Request, error) {
+// buildHTTPRequest 构建HTTP请求，pageReq 携带分页参数；url、params、headers、body 中的模板按 data 渲染，
+// 返回的错误中 secret 取值已替换为 ***
+func (p *Processor) buildHTTPRequest(ctx context.Context, api *model.APIInfo, pageReq *pageRequest, data templateData) (*http.Request, error) {
 	var req *http.Request
 	var err error
 
+	attempt := data.Attempt
+	tmpl, err := renderRequest(api, data)
+	if err != nil {
+		p.Log.Error("Failed to render request template",
+			zap.String("source", api.Source),
+			zap.String("category", api.Category),
+			zap.Int("attempt", attempt),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+
+	// 分页参数覆盖同名的静态参数
+	params := tmpl.params
+	if len(pageReq.params) > 0 {
+		params = make(map[string]string, len(tmpl.params)+len(pageReq.params))
+		for k, v := range tmpl.params {
+			params[k] = v
+		}
+		for k, v := range pageReq.params {
+			params[k] = v
+		}
+	}
+
+	// link 模式下后续页直接使用响应给出的地址
+	target := tmpl.url
+	if pageReq.url != "" {
+		target = pageReq.url
+	}
+
 	switch strings.ToUpper(api.Method) {
 	case "GET":
-		u, _ := url.Parse(api.URL)
-		q := u.Query()
-		for k, v := range api.Params {
-			q.Set(k, v)
+		// 模板渲染后的地址（如含特殊字符的密钥）可能无法解析
+		var u *url.URL
+		if u, err = url.Parse(target); err != nil {
+			break
+		}
+		if pageReq.url == "" {
+			q := u.Query()
+			for k, v := range params {
+				q.Set(k, v)
+			}
+			u.RawQuery = q.Encode()
 		}
-		u.RawQuery = q.Encode()
 		req, err = http.NewRequestWithContext(ctx, "GET", u.String(), nil)
 
 	case "POST/JSON":
-		jsonData, err := json.Marshal(api.Params)
+		jsonData := []byte(tmpl.body)
+		if tmpl.body == "" {
+			jsonData, err = json.Marshal(params)
+		} else if !json.Valid(jsonData) {
+			err = fmt.Errorf("body did not render to valid JSON; insert values with the json function")
+		}
 		if err != nil {
-			p.Log.Error("Failed to marshal JSON params",
+			p.Log.Error("Failed to build JSON body",
 				zap.String("source", api.Source),
 				zap.String("category", api.Category),
 				zap.Int("attempt", attempt),
@@ -199,17 +518,17 @@ func (p *Processor) buildHTTPRequest(ctx context.Context, api *model.APIInfo, at
 			)
 			return nil, err
 		}
-		req, err = http.NewRequestWithContext(ctx, "POST", api.URL, bytes.NewReader(jsonData))
+		req, err = http.NewRequestWithContext(ctx, "POST", target, bytes.NewReader(jsonData))
 		if err == nil {
 			req.Header.Set("Content-Type", "application/json")
 		}
 
 	case "POST/FORM":
 		form := url.Values{}
-		for k, v := range api.Params {
+		for k, v := range params {
 			form.Set(k, v)
 		}
-		req, err = http.NewRequestWithContext(ctx, "POST", api.URL, strings.NewReader(form.Encode()))
+		req, err = http.NewRequestWithContext(ctx, "POST", target, strings.NewReader(form.Encode()))
 		if err == nil {
 			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 		}
@@ -225,6 +544,8 @@ func (p *Processor) buildHTTPRequest(ctx context.Context, api *model.APIInfo, at
 	}
 
 	if err != nil {
+		// url.Error 中带有完整地址，不暴露其中的密钥
+		err = errors.New(data.secrets.mask(err.Error()))
 		p.Log.Error("Failed to create HTTP request",
 			zap.String("method", api.Method),
 			zap.String("source", api.Source),
@@ -236,7 +557,7 @@ func (p *Processor) buildHTTPRequest(ctx context.Context, api *model.APIInfo, at
 	}
 
 	// 设置请求头
-	for k, v := range api.Headers {
+	for k, v := range tmpl.headers {
 		req.Header.Set(k, v)
 	}
 
@@ -267,36 +588,63 @@ func (p *Processor) parseAndValidateJSON(body []byte, api *model.APIInfo, attemp
 		return nil, fmt.Errorf("top-level is not JSON object")
 	}
 
-	// Required 字段校验
-	for key, want := range api.Required {
-		gotVal, exists := parsedObj[key]
-		if !exists {
+	if err := p.validateRequired(parsedObj, api, attempt); err != nil {
+		return nil, classify(ErrClassRequired, err)
+	}
+
+	return parsedObj, nil
+}
+
+// validateRequired 校验 Required 条件，遇到第一个不满足的条件即返回错误
+func (p *Processor) validateRequired(parsedObj map[string]any, api *model.APIInfo, attempt int) error {
+	for _, check := range evalRequired(parsedObj, api.Required) {
+		if check.Passed {
+			continue
+		}
+		if check.Reason == reasonMissing {
 			p.Log.Warn("Missing required field",
-				zap.String("field", key),
+				zap.String("field", check.Path),
 				zap.String("source", api.Source),
 				zap.String("category", api.Category),
 				zap.Int("attempt", attempt),
 			)
-			return nil, fmt.Errorf("missing required field: %s", key)
+			p.notifyRequiredMismatch(api, check)
+			return fmt.Errorf("missing required field: %s", check.Path)
 		}
 
-		// 转成 string 比较
-		wantStr := fmt.Sprint(want)
-		gotStr := fmt.Sprint(gotVal)
-		if gotStr != wantStr {
-			p.Log.Warn("Required field value mismatch",
-				zap.String("field", key),
-				zap.String("want", wantStr),
-				zap.String("got", gotStr),
-				zap.String("source", api.Source),
-				zap.String("category", api.Category),
-				zap.Int("attempt", attempt),
-			)
-			return nil, fmt.Errorf("field value mismatch: %s", key)
-		}
+		p.Log.Warn("Required field value mismatch",
+			zap.String("field", check.Path),
+			zap.String("op", check.Op),
+			zap.String("want", fmt.Sprint(check.Want)),
+			zap.String("got", fmt.Sprint(check.Got)),
+			zap.String("reason", check.Reason),
+			zap.String("source", api.Source),
+			zap.String("category", api.Category),
+			zap.Int("attempt", attempt),
+		)
+		p.notifyRequiredMismatch(api, check)
+		return fmt.Errorf("field value mismatch: %s %s", check.Path, check.Op)
 	}
+	return nil
+}
 
-	return parsedObj, nil
+// notifyRequiredMismatch 上游响应不满足 Required 时告警，通常意味着接口变更或被限流
+func (p *Processor) notifyRequiredMismatch(api *model.APIInfo, check RequiredCheck) {
+	p.Alerts.Notify(alert.Alert{
+		Event:    alert.EventRequiredMismatch,
+		Source:   api.Source,
+		Category: api.Category,
+		InfoType: api.InfoType,
+		APIID:    api.ID,
+		Title:    fmt.Sprintf("%s/%s response failed required check on %s", api.Source, api.Category, check.Path),
+		Message:  check.Reason,
+		Fields: map[string]string{
+			"path": check.Path,
+			"op":   check.Op,
+			"want": fmt.Sprint(check.Want),
+			"got":  fmt.Sprint(check.Got),
+		},
+	})
 }
 
 // extractData 根据配置提取数据
@@ -306,8 +654,8 @@ func (p *Processor) extractData(parsedObj map[string]any, api *model.APIInfo, at
 
 	// 检查是否配置了自定义数据字段
 	if api.DataField != "" {
-		// 使用自定义字段名
-		if dataVal, exists := parsedObj[api.DataField]; exists {
+		// 使用自定义字段路径，如 "result.list[0].items"
+		if dataVal, exists := lookupPath(parsedObj, api.DataField); exists {
 			data = p.convertToDataBson(dataVal)
 			extractionStrategy = fmt.Sprintf("custom field: %s", api.DataField)
 		} else {
@@ -323,6 +671,10 @@ func (p *Processor) extractData(parsedObj map[string]any, api *model.APIInfo, at
 		// 使用完整响应
 		data = bson.M(parsedObj)
 		extractionStrategy = "full response"
+	} else if format := responseFormat(api); isNormalizedFormat(format) {
+		// RSS/Atom/HTML 已归一化为带 items 数组的结构
+		data = bson.M(parsedObj)
+		extractionStrategy = "normalized " + format
 	} else {
 		// 默认使用 "data" 字段
 		if dataVal, exists := parsedObj["data"]; exists {
@@ -341,22 +693,39 @@ func (p *Processor) extractData(parsedObj map[string]any, api *model.APIInfo, at
 	return data, extractionStrategy, nil
 }
 
-// saveToDatabase 保存数据到数据库
-func (p *Processor) saveToDatabase(ctx context.Context, data bson.M, api *model.APIInfo, contentColl *mongo.Collection, now time.Time, attempt int, extractionStrategy string) bool {
+// saveToDatabase 保存数据到数据库；多页结果按 Pagination.Store 合并为一条或逐页关联存储
+func (p *Processor) saveToDatabase(ctx context.Context, pages []fetchedPage, api *model.APIInfo, contentColl *mongo.Collection, now time.Time, attempt int) bool {
 	// 使用 Asia/Shanghai 生成 date 字段（YYYY-MM-DD）
 	shanghai, _ := time.LoadLocation("Asia/Shanghai")
 
-	doc := model.CrawlResult{
-		Date:      now.In(shanghai).Format("2006-01-02"),
-		Source:    api.Source,
-		Category:  api.Category,
-		InfoType:  api.InfoType,
-		Data:      data,
-		Processed: false,
-		CreatedAt: time.Now().UTC(),
+	newDoc := func(data bson.M, charset model.DetectedCharset) model.CrawlResult {
+		return model.CrawlResult{
+			Date:      now.In(shanghai).Format("2006-01-02"),
+			Source:    api.Source,
+			Category:  api.Category,
+			InfoType:  api.InfoType,
+			Data:      data,
+			Processed: false,
+			CreatedAt: time.Now().UTC(),
+			Encoding:  &charset,
+		}
+	}
+
+	var docs []any
+	if len(pages) > 1 && api.Pagination != nil && api.Pagination.Store == "linked" {
+		group := primitive.NewObjectID().Hex()
+		for i, page := range pages {
+			doc := newDoc(page.data, page.charset)
+			doc.PageGroup = group
+			doc.Page = i + 1
+			doc.PageCount = len(pages)
+			docs = append(docs, doc)
+		}
+	} else {
+		docs = append(docs, newDoc(mergePages(pages), pages[0].charset))
 	}
 
-	_, err := contentColl.InsertOne(ctx, doc)
+	_, err := contentColl.InsertMany(ctx, docs)
 	if err != nil {
 		p.Log.Error("Failed to insert document",
 			zap.String("source", api.Source),
@@ -366,17 +735,54 @@ func (p *Processor) saveToDatabase(ctx context.Context, data bson.M, api *model.
 		)
 		return false // 数据库插入失败，可以重试
 	}
+	metrics.DocumentsInserted(api.Source, api.Category, api.InfoType, len(docs))
 
 	p.Log.Info("Successfully processed API",
 		zap.String("source", api.Source),
 		zap.String("category", api.Category),
 		zap.Int("attempt", attempt),
-		zap.String("extractionStrategy", extractionStrategy),
+		zap.Int("pages", len(pages)),
+		zap.String("extractionStrategy", pages[0].strategy),
 	)
 
 	return true // 成功
 }
 
+// mergePages 合并多页数据：各页都含 items 数组时拼接条目（其他字段取第一页），否则按页放入 {"pages": [...]}
+func mergePages(pages []fetchedPage) bson.M {
+	if len(pages) == 1 {
+		return pages[0].data
+	}
+
+	var items []any
+	for _, page := range pages {
+		pageItems, ok := page.data["items"].([]any)
+		if !ok {
+			all := make([]any, len(pages))
+			for i, pg := range pages {
+				all[i] = pg.data
+			}
+			return bson.M{"pages": all}
+		}
+		items = append(items, pageItems...)
+	}
+
+	merged := bson.M{}
+	for k, v := range pages[0].data {
+		merged[k] = v
+	}
+	merged["items"] = items
+	return merged
+}
+
+// countItems 统计提取结果中的条目数，无法判断时返回 -1
+func countItems(data bson.M) int {
+	if items, ok := data["items"].([]any); ok {
+		return len(items)
+	}
+	return -1
+}
+
 // convertToDataBson 将不同类型的数据转换为 bson.M
 func (p *Processor) convertToDataBson(dataVal any) bson.M {
 	switch v := dataVal.(type) {
@@ -0,0 +1,100 @@
+package processor
+
+import (
+	"api-fetch/internal/api_fetch/model"
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// recordAttemptTimeout 写入抓取记录的超时，服务停止时也尽量写完
+const recordAttemptTimeout = 5 * time.Second
+
+// attemptStats 一次抓取尝试过程中累计的指标
+type attemptStats struct {
+	startedAt  time.Time
+	url        string
+	statusCode int
+	latency    time.Duration
+	bodySize   int
+	pages      int
+	items      int
+	strategy   string
+	cond       *conditional // 条件请求状态，可为空
+	slot       *fetchSlot   // 本次尝试占用的全局槽位，可为空
+}
+
+func newAttemptStats() *attemptStats {
+	return &attemptStats{startedAt: time.Now(), items: -1}
+}
+
+// addPages 记录成功抓取的各页结果
+func (s *attemptStats) addPages(pages []fetchedPage) {
+	s.pages = len(pages)
+	if len(pages) > 0 {
+		s.strategy = pages[0].strategy
+	}
+	s.items = 0
+	for _, page := range pages {
+		n := countItems(page.data)
+		if n < 0 {
+			s.items = -1
+			return
+		}
+		s.items += n
+	}
+}
+
+// attempt 生成 fetch_attempts 记录
+func (s *attemptStats) attempt(api *model.APIInfo, runID string, attempt int, err error) model.FetchAttempt {
+	rec := model.FetchAttempt{
+		RunID:      runID,
+		APIID:      api.ID,
+		Source:     api.Source,
+		Category:   api.Category,
+		InfoType:   api.InfoType,
+		Attempt:    attempt,
+		Success:    err == nil,
+		URL:        s.url,
+		StatusCode: s.statusCode,
+		LatencyMS:  s.latency.Milliseconds(),
+		BodySize:   s.bodySize,
+		Pages:      s.pages,
+		ItemCount:  s.items,
+		Strategy:   s.strategy,
+		StartedAt:  s.startedAt.UTC(),
+		FinishedAt: time.Now().UTC(),
+	}
+	if err != nil {
+		rec.ErrorClass = ErrorClass(err)
+		rec.Error = err.Error()
+	}
+	return rec
+}
+
+// recordAttempt 写入 fetch_attempts；失败只记日志，不影响抓取结果
+func (p *Processor) recordAttempt(ctx context.Context, rec model.FetchAttempt) {
+	if p.Stores == nil || p.Stores.FetchAttempts == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), recordAttemptTimeout)
+	defer cancel()
+
+	if _, err := p.Stores.FetchAttempts.InsertOne(ctx, rec); err != nil {
+		p.Log.Warn("Failed to record fetch attempt",
+			zap.String("source", rec.Source),
+			zap.String("category", rec.Category),
+			zap.Int("attempt", rec.Attempt),
+			zap.Error(err),
+		)
+	}
+}
+
+// statusClass 非 2xx 响应导致的解析/提取失败归为 http_status
+func statusClass(statusCode int, class string) string {
+	if statusCode < 200 || statusCode >= 300 {
+		return ErrClassHTTPStatus
+	}
+	return class
+}
@@ -0,0 +1,613 @@
+package processor
+
+import (
+	"api-fetch/internal/api_fetch/model"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// 认证方式
+const (
+	AuthOAuth2 = "oauth2"  // OAuth2 client credentials
+	AuthHMAC   = "hmac"    // HMAC-SHA256 请求签名
+	AuthAPIKey = "api_key" // 多个 API Key 轮换
+)
+
+const (
+	// tokenExpiryDelta 令牌在过期前多久刷新，避免请求途中过期
+	tokenExpiryDelta = time.Minute
+	// maxTokenResponse 令牌响应体上限
+	maxTokenResponse = 1 << 20
+
+	defaultHMACHeader          = "Authorization"
+	defaultHMACValue           = "HMAC-SHA256 {{.KeyID}}:{{.Signature}}"
+	defaultHMACTimestampHeader = "X-Timestamp"
+
+	defaultKeyCooldown = time.Hour
+)
+
+// defaultRotateStatuses 默认视为 API Key 配额用尽的状态码
+var defaultRotateStatuses = []int{http.StatusTooManyRequests}
+
+// authenticator 一种请求认证方式，同一 API 的请求共用一个实例，须并发安全
+type authenticator interface {
+	// apply 在请求构建完成后添加认证信息，data 用于渲染凭据模板
+	apply(ctx context.Context, req *http.Request, data templateData) error
+	// rejected 上游拒绝本次请求的凭据时调用；返回 true 表示已更换凭据，可立即重发。resend 为该页已重发的次数
+	rejected(resp *http.Response, resend int, now time.Time) bool
+}
+
+// authFactories 认证方式注册表，新增认证方式在此注册；工厂负责校验配置，client 为空时只做校验
+var authFactories = map[string]func(cfg *model.Auth, client *http.Client) (authenticator, error){
+	AuthOAuth2: newOAuth2Auth,
+	AuthHMAC:   newHMACAuth,
+	AuthAPIKey: newAPIKeyAuth,
+}
+
+func newAuthenticator(cfg *model.Auth, client *http.Client) (authenticator, error) {
+	factory, ok := authFactories[cfg.Type]
+	if !ok {
+		return nil, fmt.Errorf("auth.type must be one of oauth2, hmac, api_key, got %q", cfg.Type)
+	}
+	return factory(cfg, client)
+}
+
+// validateAuth 校验 API 的认证配置，client_secret、hmac.secret 与 api_key.keys 须为 secret 引用
+func validateAuth(cfg *model.Auth) error {
+	if cfg == nil {
+		return nil
+	}
+	if _, err := newAuthenticator(cfg, nil); err != nil {
+		return err
+	}
+	switch cfg.Type {
+	case AuthOAuth2:
+		return requireSecretRef("auth.oauth2.client_secret", cfg.OAuth2.ClientSecret)
+	case AuthHMAC:
+		return requireSecretRef("auth.hmac.secret", cfg.HMAC.Secret)
+	case AuthAPIKey:
+		for i, k := range cfg.APIKey.Keys {
+			if err := requireSecretRef(fmt.Sprintf("auth.api_key.keys[%d]", i), k); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Authenticators 按 API 缓存认证状态（令牌、当前使用的密钥），认证配置变化后重建；nil 时每次请求重新创建
+type Authenticators struct {
+	client *http.Client // 请求令牌使用的客户端
+
+	mu    sync.Mutex
+	cache map[string]*cachedAuth
+}
+
+type cachedAuth struct {
+	sig  string // 认证配置的 JSON，用于发现配置变化
+	auth authenticator
+}
+
+// NewAuthenticators 创建认证状态缓存
+func NewAuthenticators(client *http.Client) *Authenticators {
+	return &Authenticators{client: client, cache: make(map[string]*cachedAuth)}
+}
+
+// get 返回 API 的认证器，未配置认证时返回 nil
+func (a *Authenticators) get(api *model.APIInfo) (authenticator, error) {
+	if api.Auth == nil {
+		return nil, nil
+	}
+	if a == nil {
+		return newAuthenticator(api.Auth, http.DefaultClient)
+	}
+	raw, err := json.Marshal(api.Auth)
+	if err != nil {
+		return nil, err
+	}
+	key, sig := fetchStateKey(api), string(raw)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if c, ok := a.cache[key]; ok && c.sig == sig {
+		return c.auth, nil
+	}
+	auth, err := newAuthenticator(api.Auth, a.client)
+	if err != nil {
+		return nil, err
+	}
+	a.cache[key] = &cachedAuth{sig: sig, auth: auth}
+	return auth, nil
+}
+
+// applyAuth 为请求添加 API 配置的认证信息，data 与构建请求时相同
+func (p *Processor) applyAuth(ctx context.Context, api *model.APIInfo, req *http.Request, data templateData) error {
+	auth, err := p.Auth.get(api)
+	if err != nil || auth == nil {
+		return err
+	}
+	return auth.apply(ctx, req, data)
+}
+
+// authRejected 上游拒绝凭据且已更换时返回 true
+func (p *Processor) authRejected(api *model.APIInfo, resp *http.Response, resend int) bool {
+	auth, err := p.Auth.get(api)
+	if err != nil || auth == nil {
+		return false
+	}
+	return auth.rejected(resp, resend, time.Now())
+}
+
+// previewFields 按当前时间试渲染凭据模板，prefix 用于错误信息
+func previewFields(prefix string, fields map[string]string) error {
+	data := newTemplateData(&model.APIInfo{}, time.Now(), 1, &pageRequest{})
+	for name, text := range fields {
+		if _, err := previewTemplate(prefix+"."+name, text, data); err != nil {
+			return fmt.Errorf("%s.%s: %w", prefix, name, err)
+		}
+	}
+	return nil
+}
+
+// redactAuth 试运行结果中隐藏 API Key；Authorization 等请求头已由 describeRequest 脱敏
+func redactAuth(out *DryRunRequest, cfg *model.Auth) {
+	if out == nil || cfg == nil || cfg.APIKey == nil || cfg.Type != AuthAPIKey {
+		return
+	}
+	name := cfg.APIKey.Name
+	if cfg.APIKey.In == "query" {
+		if u, err := url.Parse(out.URL); err == nil && u.Query().Has(name) {
+			q := u.Query()
+			q.Set(name, "***")
+			u.RawQuery = q.Encode()
+			out.URL = u.String()
+		}
+		return
+	}
+	if out.Headers.Get(name) != "" {
+		out.Headers.Set(name, "***")
+	}
+}
+
+// oauth2Auth OAuth2 client credentials，缓存令牌直到过期前 tokenExpiryDelta
+type oauth2Auth struct {
+	cfg    *model.OAuth2Auth
+	client *http.Client
+
+	mu     sync.Mutex
+	value  string    // Authorization 请求头的值，如 "Bearer xxx"
+	expiry time.Time // 令牌过期时间，零值表示上游未给出
+}
+
+func newOAuth2Auth(cfg *model.Auth, client *http.Client) (authenticator, error) {
+	c := cfg.OAuth2
+	if c == nil {
+		return nil, fmt.Errorf("auth.oauth2 is required for type oauth2")
+	}
+	u, err := url.Parse(c.TokenURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("auth.oauth2.token_url must be an absolute http(s) url, got %q", c.TokenURL)
+	}
+	if c.ClientID == "" || c.ClientSecret == "" {
+		return nil, fmt.Errorf("auth.oauth2.client_id and client_secret are required")
+	}
+	if c.AuthStyle != "" && c.AuthStyle != "header" && c.AuthStyle != "params" {
+		return nil, fmt.Errorf("auth.oauth2.auth_style must be header or params, got %q", c.AuthStyle)
+	}
+	fields := map[string]string{"client_id": c.ClientID, "client_secret": c.ClientSecret}
+	for k, v := range c.Params {
+		fields["params."+k] = v
+	}
+	if err := previewFields("auth.oauth2", fields); err != nil {
+		return nil, err
+	}
+	return &oauth2Auth{cfg: c, client: client}, nil
+}
+
+func (o *oauth2Auth) apply(ctx context.Context, req *http.Request, data templateData) error {
+	value, err := o.token(ctx, data)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", value)
+	return nil
+}
+
+// token 返回缓存的令牌，即将过期或已被拒绝时重新获取；持锁获取，并发请求只换一次令牌
+func (o *oauth2Auth) token(ctx context.Context, data templateData) (string, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.value != "" && (o.expiry.IsZero() || time.Now().Before(o.expiry.Add(-tokenExpiryDelta))) {
+		return o.value, nil
+	}
+	value, expiry, err := o.fetch(ctx, data)
+	if err != nil {
+		return "", err
+	}
+	o.value, o.expiry = value, expiry
+	return value, nil
+}
+
+// fetch 向 token_url 请求新令牌；网络错误与非 2xx 响应按抓取错误分类，由重试策略处理
+func (o *oauth2Auth) fetch(ctx context.Context, data templateData) (string, time.Time, error) {
+	var zero time.Time
+	id, err := renderTemplate("auth.oauth2.client_id", o.cfg.ClientID, data)
+	if err != nil {
+		return "", zero, err
+	}
+	secret, err := renderTemplate("auth.oauth2.client_secret", o.cfg.ClientSecret, data)
+	if err != nil {
+		return "", zero, err
+	}
+	params, err := renderMap("auth.oauth2.params", o.cfg.Params, data)
+	if err != nil {
+		return "", zero, err
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(o.cfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(o.cfg.Scopes, " "))
+	}
+	for k, v := range params {
+		form.Set(k, v)
+	}
+	if o.cfg.AuthStyle == "params" {
+		form.Set("client_id", id)
+		form.Set("client_secret", secret)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", o.cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", zero, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if o.cfg.AuthStyle != "params" {
+		// RFC 6749 2.3.1：Basic 认证的用户名与密码先做表单编码
+		req.SetBasicAuth(url.QueryEscape(id), url.QueryEscape(secret))
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return "", zero, classify(ErrClassNetwork, fmt.Errorf("oauth2 token request: %w", err))
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxTokenResponse))
+	if err != nil {
+		return "", zero, classify(ErrClassNetwork, fmt.Errorf("oauth2 token response: %w", err))
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := excerpt(body, 200)
+		return "", zero, withResponse(ErrClassHTTPStatus, fmt.Errorf("oauth2 token endpoint responded %s: %s", resp.Status, msg), resp)
+	}
+
+	var tok struct {
+		AccessToken string      `json:"access_token"`
+		TokenType   string      `json:"token_type"`
+		ExpiresIn   json.Number `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &tok); err != nil {
+		return "", zero, classify(ErrClassParse, fmt.Errorf("oauth2 token response: %w", err))
+	}
+	if tok.AccessToken == "" {
+		return "", zero, classify(ErrClassParse, fmt.Errorf("oauth2 token response has no access_token"))
+	}
+	tokenType := tok.TokenType
+	if tokenType == "" || strings.EqualFold(tokenType, "bearer") {
+		tokenType = "Bearer"
+	}
+	var expiry time.Time
+	if secs, err := tok.ExpiresIn.Int64(); err == nil && secs > 0 {
+		expiry = time.Now().Add(time.Duration(secs) * time.Second)
+	}
+	return tokenType + " " + tok.AccessToken, expiry, nil
+}
+
+// rejected 401 时丢弃本次使用的令牌，重发一次
+func (o *oauth2Auth) rejected(resp *http.Response, resend int, _ time.Time) bool {
+	if resp.StatusCode != http.StatusUnauthorized || resend > 0 {
+		return false
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	// 其他请求已换过令牌时不再丢弃
+	if resp.Request != nil && resp.Request.Header.Get("Authorization") == o.value {
+		o.value = ""
+	}
+	return true
+}
+
+// hmacAuth 对 method、path、时间戳（及可选的 nonce、请求体哈希）逐行拼接后做 HMAC-SHA256
+type hmacAuth struct {
+	cfg             *model.HMACAuth
+	header          string
+	timestampHeader string
+	value           *template.Template
+}
+
+// hmacValue 签名请求头的值模板可用的变量
+type hmacValue struct {
+	KeyID     string
+	Signature string
+	Timestamp string
+	Nonce     string
+}
+
+func newHMACAuth(cfg *model.Auth, _ *http.Client) (authenticator, error) {
+	c := cfg.HMAC
+	if c == nil {
+		return nil, fmt.Errorf("auth.hmac is required for type hmac")
+	}
+	if c.Secret == "" {
+		return nil, fmt.Errorf("auth.hmac.secret is required")
+	}
+	if c.Encoding != "" && c.Encoding != "hex" && c.Encoding != "base64" {
+		return nil, fmt.Errorf("auth.hmac.encoding must be hex or base64, got %q", c.Encoding)
+	}
+	if err := previewFields("auth.hmac", map[string]string{"key_id": c.KeyID, "secret": c.Secret}); err != nil {
+		return nil, err
+	}
+	a := &hmacAuth{
+		cfg:             c,
+		header:          c.Header,
+		timestampHeader: c.TimestampHeader,
+	}
+	if a.header == "" {
+		a.header = defaultHMACHeader
+	}
+	if a.timestampHeader == "" {
+		a.timestampHeader = defaultHMACTimestampHeader
+	}
+	value := c.Value
+	if value == "" {
+		value = defaultHMACValue
+	}
+	tmpl, err := template.New("auth.hmac.value").Option("missingkey=error").Parse(value)
+	if err == nil {
+		err = tmpl.Execute(io.Discard, hmacValue{})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("auth.hmac.value: %w", err)
+	}
+	a.value = tmpl
+	return a, nil
+}
+
+func (h *hmacAuth) apply(_ context.Context, req *http.Request, data templateData) error {
+	keyID, err := renderTemplate("auth.hmac.key_id", h.cfg.KeyID, data)
+	if err != nil {
+		return err
+	}
+	secret, err := renderTemplate("auth.hmac.secret", h.cfg.Secret, data)
+	if err != nil {
+		return err
+	}
+
+	// 时间戳取发送时间而非运行时间，异步重试时不会因时间偏差被拒绝
+	v := hmacValue{KeyID: keyID, Timestamp: formatTimestamp(time.Now(), h.cfg.TimestampFormat)}
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	lines := []string{req.Method, path, v.Timestamp}
+	if h.cfg.NonceHeader != "" {
+		if v.Nonce, err = randomHex(16); err != nil {
+			return err
+		}
+		lines = append(lines, v.Nonce)
+	}
+	if h.cfg.SignBody {
+		sum, err := bodyHash(req)
+		if err != nil {
+			return err
+		}
+		lines = append(lines, sum)
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strings.Join(lines, "\n")))
+	if h.cfg.Encoding == "base64" {
+		v.Signature = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	} else {
+		v.Signature = hex.EncodeToString(mac.Sum(nil))
+	}
+
+	var buf bytes.Buffer
+	if err := h.value.Execute(&buf, v); err != nil {
+		return err
+	}
+	req.Header.Set(h.timestampHeader, v.Timestamp)
+	if h.cfg.NonceHeader != "" {
+		req.Header.Set(h.cfg.NonceHeader, v.Nonce)
+	}
+	req.Header.Set(h.header, buf.String())
+	return nil
+}
+
+// rejected 签名每次重新计算，没有可更换的凭据
+func (h *hmacAuth) rejected(*http.Response, int, time.Time) bool { return false }
+
+// formatTimestamp 按 unix / unix_milli / rfc3339 或 Go layout 格式化时间戳，layout 按 UTC 输出
+func formatTimestamp(t time.Time, format string) string {
+	switch format {
+	case "", "unix":
+		return strconv.FormatInt(t.Unix(), 10)
+	case "unix_milli":
+		return strconv.FormatInt(t.UnixMilli(), 10)
+	case "rfc3339":
+		return t.UTC().Format(time.RFC3339)
+	default:
+		return t.UTC().Format(format)
+	}
+}
+
+// bodyHash 请求体的 SHA-256（十六进制），无请求体时为空串的哈希
+func bodyHash(req *http.Request) (string, error) {
+	h := sha256.New()
+	if req.GetBody != nil {
+		rc, err := req.GetBody()
+		if err != nil {
+			return "", err
+		}
+		defer rc.Close()
+		if _, err := io.Copy(h, rc); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// apiKeyAuth 持续使用当前密钥，配额用尽时停用一段时间并按顺序切换到下一个可用的密钥
+type apiKeyAuth struct {
+	cfg      *model.APIKeyAuth
+	statuses map[int]struct{}
+	cooldown time.Duration
+
+	mu      sync.Mutex
+	current int
+	until   []time.Time    // 每个密钥停用到何时
+	used    map[string]int // 渲染后的密钥 -> 下标，用于判断被拒绝的是哪个密钥
+}
+
+func newAPIKeyAuth(cfg *model.Auth, _ *http.Client) (authenticator, error) {
+	c := cfg.APIKey
+	if c == nil {
+		return nil, fmt.Errorf("auth.api_key is required for type api_key")
+	}
+	if len(c.Keys) == 0 {
+		return nil, fmt.Errorf("auth.api_key.keys must not be empty")
+	}
+	if c.Name == "" {
+		return nil, fmt.Errorf("auth.api_key.name is required")
+	}
+	if c.In != "" && c.In != "header" && c.In != "query" {
+		return nil, fmt.Errorf("auth.api_key.in must be header or query, got %q", c.In)
+	}
+	fields := make(map[string]string, len(c.Keys))
+	for i, k := range c.Keys {
+		if k == "" {
+			return nil, fmt.Errorf("auth.api_key.keys[%d] must not be empty", i)
+		}
+		fields[fmt.Sprintf("keys[%d]", i)] = k
+	}
+	if err := previewFields("auth.api_key", fields); err != nil {
+		return nil, err
+	}
+
+	a := &apiKeyAuth{
+		cfg:      c,
+		cooldown: defaultKeyCooldown,
+		until:    make([]time.Time, len(c.Keys)),
+		used:     make(map[string]int, len(c.Keys)),
+	}
+	statuses := c.RotateStatuses
+	if len(statuses) == 0 {
+		statuses = defaultRotateStatuses
+	}
+	a.statuses = make(map[int]struct{}, len(statuses))
+	for _, code := range statuses {
+		if code < 400 || code > 599 {
+			return nil, fmt.Errorf("auth.api_key.rotate_statuses: invalid status code %d", code)
+		}
+		a.statuses[code] = struct{}{}
+	}
+	if c.Cooldown != "" {
+		d, err := time.ParseDuration(c.Cooldown)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("auth.api_key.cooldown must be a positive duration, got %q", c.Cooldown)
+		}
+		a.cooldown = d
+	}
+	return a, nil
+}
+
+func (a *apiKeyAuth) apply(_ context.Context, req *http.Request, data templateData) error {
+	idx := a.pick(time.Now())
+	key, err := renderTemplate(fmt.Sprintf("auth.api_key.keys[%d]", idx), a.cfg.Keys[idx], data)
+	if err != nil {
+		return err
+	}
+	a.mu.Lock()
+	a.used[key] = idx
+	a.mu.Unlock()
+
+	if a.cfg.In == "query" {
+		q := req.URL.Query()
+		q.Set(a.cfg.Name, key)
+		req.URL.RawQuery = q.Encode()
+		return nil
+	}
+	req.Header.Set(a.cfg.Name, a.cfg.Prefix+key)
+	return nil
+}
+
+// pick 从当前密钥开始找第一个可用的；全部停用时使用最早恢复的，由上游决定是否放行
+func (a *apiKeyAuth) pick(now time.Time) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	n := len(a.until)
+	for i := 0; i < n; i++ {
+		idx := (a.current + i) % n
+		if !now.Before(a.until[idx]) {
+			a.current = idx
+			return idx
+		}
+	}
+	best := a.current
+	for i, t := range a.until {
+		if t.Before(a.until[best]) {
+			best = i
+		}
+	}
+	return best
+}
+
+// rejected 配额用尽时停用本次使用的密钥（Retry-After 优先于 cooldown），还有可用的密钥时立即重发
+func (a *apiKeyAuth) rejected(resp *http.Response, resend int, now time.Time) bool {
+	if _, ok := a.statuses[resp.StatusCode]; !ok || resp.Request == nil {
+		return false
+	}
+	var key string
+	if a.cfg.In == "query" {
+		key = resp.Request.URL.Query().Get(a.cfg.Name)
+	} else {
+		key = strings.TrimPrefix(resp.Request.Header.Get(a.cfg.Name), a.cfg.Prefix)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	idx, ok := a.used[key]
+	if !ok {
+		return false
+	}
+	cooldown := a.cooldown
+	if after := parseRetryAfter(resp.Header.Get("Retry-After"), now); after > 0 {
+		cooldown = after
+	}
+	a.until[idx] = now.Add(cooldown)
+	if a.current == idx {
+		a.current = (idx + 1) % len(a.until)
+	}
+	if resend >= len(a.until)-1 {
+		return false
+	}
+	for _, t := range a.until {
+		if !now.Before(t) {
+			return true
+		}
+	}
+	return false
+}
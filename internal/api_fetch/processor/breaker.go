@@ -0,0 +1,261 @@
+package processor
+
+import (
+	"api-fetch/internal/api_fetch/alert"
+	"api-fetch/internal/api_fetch/metrics"
+	"api-fetch/internal/api_fetch/model"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 熔断器状态
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half_open" // 冷却结束，放行一次探测请求
+)
+
+// 熔断范围
+const (
+	BreakerScopeHost = "host" // 同一 host 下的 API 共用一个熔断器
+	BreakerScopeAPI  = "api"  // 每个 API 单独熔断
+)
+
+// 默认参数
+const (
+	defaultBreakerThreshold = 5
+	defaultBreakerOpenFor   = 5 * time.Minute
+)
+
+// BreakerConfig 熔断配置（config.yaml 中 fetch.breaker 段）
+type BreakerConfig struct {
+	Disabled         bool          `yaml:"disabled"`
+	Scope            string        `yaml:"scope"`             // host（默认）| api
+	FailureThreshold int           `yaml:"failure_threshold"` // 连续失败多少次后熔断，默认 5
+	OpenDuration     time.Duration `yaml:"open_duration"`     // 熔断后多久放行探测请求，默认 5m
+}
+
+// BreakerState 熔断器状态快照
+type BreakerState struct {
+	Key              string     `json:"key"` // host 或 API ID
+	State            string     `json:"state"`
+	ConsecutiveFails int        `json:"consecutive_failures"`
+	LastError        string     `json:"last_error,omitempty"`
+	OpenedAt         *time.Time `json:"opened_at,omitempty"`
+	NextProbeAt      *time.Time `json:"next_probe_at,omitempty"` // 熔断中时，下次放行探测请求的时间
+	Skipped          int        `json:"skipped"`                 // 本次熔断期间跳过的抓取次数
+}
+
+// breaker 单个熔断器，由 Breakers 加锁访问
+type breaker struct {
+	state     string
+	fails     int
+	lastError string
+	openedAt  time.Time
+	probing   bool // 半开状态下已放行的探测请求尚未返回
+	skipped   int
+}
+
+// Breakers 按 host 或 API 维护熔断器；nil 时不熔断
+type Breakers struct {
+	cfg    BreakerConfig
+	alerts *alert.Manager
+
+	mu       sync.Mutex
+	breakers map[string]*breaker
+}
+
+// NewBreakers 根据配置创建熔断器集合，Disabled 时返回 nil
+func NewBreakers(cfg BreakerConfig, alerts *alert.Manager) *Breakers {
+	if cfg.Disabled {
+		return nil
+	}
+	if cfg.Scope != BreakerScopeAPI {
+		cfg.Scope = BreakerScopeHost
+	}
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = defaultBreakerThreshold
+	}
+	if cfg.OpenDuration <= 0 {
+		cfg.OpenDuration = defaultBreakerOpenFor
+	}
+	return &Breakers{cfg: cfg, alerts: alerts, breakers: make(map[string]*breaker)}
+}
+
+// key 熔断器的键：host 范围取 API 地址的 host，api 范围取 API ID
+func (b *Breakers) key(api *model.APIInfo) string {
+	if b.cfg.Scope == BreakerScopeAPI {
+		if api.ID != "" {
+			return api.ID
+		}
+		return processorKey(api.Source, api.Category, api.InfoType)
+	}
+	u, err := url.Parse(api.URL)
+	if err != nil || u.Hostname() == "" {
+		return api.URL
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// allow 是否放行本次抓取；熔断中返回 false，冷却结束后只放行一次探测请求
+func (b *Breakers) allow(api *model.APIInfo, now time.Time) bool {
+	if b == nil {
+		return true
+	}
+	key := b.key(api)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	br, ok := b.breakers[key]
+	if !ok {
+		return true
+	}
+	switch br.state {
+	case BreakerOpen:
+		if now.Sub(br.openedAt) < b.cfg.OpenDuration {
+			br.skipped++
+			return false
+		}
+		br.state = BreakerHalfOpen
+		br.probing = true
+		metrics.BreakerState(key, br.state)
+		return true
+	case BreakerHalfOpen:
+		if br.probing {
+			br.skipped++
+			return false
+		}
+		br.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// report 记录本次抓取结果；只有网络、超时、5xx / 429 等上游故障计入失败，其他错误视为上游可用
+func (b *Breakers) report(api *model.APIInfo, err error, now time.Time) {
+	if b == nil {
+		return
+	}
+	key := b.key(api)
+	failed := upstreamFailure(err)
+
+	b.mu.Lock()
+	br, ok := b.breakers[key]
+	if ok && ErrorClass(err) == ErrClassCanceled {
+		// 服务停止导致的失败不说明上游状态，只释放探测名额
+		br.probing = false
+		b.mu.Unlock()
+		return
+	}
+	if !ok {
+		if !failed {
+			b.mu.Unlock()
+			return
+		}
+		br = &breaker{state: BreakerClosed}
+		b.breakers[key] = br
+	}
+	br.probing = false
+	if !failed {
+		br.state, br.fails, br.lastError, br.skipped = BreakerClosed, 0, "", 0
+		metrics.BreakerState(key, br.state)
+		b.mu.Unlock()
+		return
+	}
+
+	br.fails++
+	br.lastError = err.Error()
+	opened := false
+	if br.state == BreakerHalfOpen || (br.state == BreakerClosed && br.fails >= b.cfg.FailureThreshold) {
+		opened = br.state == BreakerClosed
+		br.state = BreakerOpen
+		br.openedAt = now
+		metrics.BreakerState(key, br.state)
+	}
+	fails, lastError := br.fails, br.lastError
+	b.mu.Unlock()
+
+	// 探测失败重新计时，只在由关闭转为熔断时告警
+	if opened {
+		b.alerts.Notify(alert.Alert{
+			Event:    alert.EventCircuitOpen,
+			Source:   api.Source,
+			Category: api.Category,
+			InfoType: api.InfoType,
+			Title:    fmt.Sprintf("Circuit opened for %s", key),
+			Message:  fmt.Sprintf("Fetches are skipped for %s after %d consecutive upstream failures.", b.cfg.OpenDuration, fails),
+			Fields: map[string]string{
+				"key":                  key,
+				"consecutive_failures": strconv.Itoa(fails),
+				"last_error":           lastError,
+			},
+		})
+	}
+}
+
+// upstreamFailure 错误是否说明上游不可用
+func upstreamFailure(err error) bool {
+	switch ErrorClass(err) {
+	case ErrClassNetwork, ErrClassTimeout:
+		return true
+	case ErrClassHTTPStatus:
+		status, _ := responseInfo(err)
+		return status == 0 || status >= 500 || status == 429
+	default:
+		return false
+	}
+}
+
+// States 所有熔断器的状态，熔断中的排在前面
+func (b *Breakers) States() []BreakerState {
+	out := []BreakerState{}
+	if b == nil {
+		return out
+	}
+	b.mu.Lock()
+	for key, br := range b.breakers {
+		st := BreakerState{
+			Key:              key,
+			State:            br.state,
+			ConsecutiveFails: br.fails,
+			LastError:        br.lastError,
+			Skipped:          br.skipped,
+		}
+		if br.state != BreakerClosed {
+			opened := br.openedAt
+			next := opened.Add(b.cfg.OpenDuration)
+			st.OpenedAt, st.NextProbeAt = &opened, &next
+		}
+		out = append(out, st)
+	}
+	b.mu.Unlock()
+
+	rank := map[string]int{BreakerOpen: 0, BreakerHalfOpen: 1, BreakerClosed: 2}
+	sort.Slice(out, func(i, j int) bool {
+		if rank[out[i].State] != rank[out[j].State] {
+			return rank[out[i].State] < rank[out[j].State]
+		}
+		return out[i].Key < out[j].Key
+	})
+	return out
+}
+
+// Reset 手动关闭熔断器，返回是否存在
+func (b *Breakers) Reset(key string) bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.breakers[key]; !ok {
+		return false
+	}
+	delete(b.breakers, key)
+	metrics.BreakerState(key, BreakerClosed)
+	return true
+}
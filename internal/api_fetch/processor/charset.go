@@ -0,0 +1,119 @@
+package processor
+
+import (
+	"api-fetch/internal/api_fetch/model"
+	"bytes"
+	"fmt"
+	"mime"
+	"regexp"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// 编码来源，记录在抓取结果中便于排查乱码
+const (
+	charsetFromOverride    = "override"
+	charsetFromBOM         = "bom"
+	charsetFromContentType = "content-type"
+	charsetFromDocument    = "document" // XML 声明或 HTML <meta>
+	charsetFromGuess       = "guess"
+	charsetFromDefault     = "default"
+)
+
+// declaredCharsetRe 匹配 XML 声明的 encoding 或 HTML <meta> 中的 charset
+var declaredCharsetRe = regexp.MustCompile(`(?i)(?:<\?xml[^>]*encoding|<meta[^>]*charset)\s*=\s*["']?\s*([a-zA-Z0-9_:.\-]+)`)
+
+// validateCharset 校验 APIInfo.Charset 配置
+func validateCharset(name string) error {
+	if name == "" {
+		return nil
+	}
+	if _, _, ok := lookupCharset(name); !ok {
+		return fmt.Errorf("unsupported charset: %s", name)
+	}
+	return nil
+}
+
+// decodeBody 检测响应编码并转换为 UTF-8
+// 判断顺序：APIInfo.Charset 覆盖 > BOM > Content-Type > 文档内声明 > 非法 UTF-8 时按 GB18030 猜测 > UTF-8
+func decodeBody(body []byte, contentType string, api *model.APIInfo) ([]byte, model.DetectedCharset, error) {
+	// 1. 配置覆盖
+	if api.Charset != "" {
+		enc, name, ok := lookupCharset(api.Charset)
+		if !ok {
+			return nil, model.DetectedCharset{}, fmt.Errorf("unsupported charset override: %s", api.Charset)
+		}
+		return transcode(stripUTF8BOM(body), enc, model.DetectedCharset{Name: name, Source: charsetFromOverride})
+	}
+
+	// 2. BOM
+	switch {
+	case bytes.HasPrefix(body, []byte{0xEF, 0xBB, 0xBF}):
+		return body[3:], model.DetectedCharset{Name: "utf-8", Source: charsetFromBOM}, nil
+	case bytes.HasPrefix(body, []byte{0xFF, 0xFE}):
+		return transcode(body, unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM), model.DetectedCharset{Name: "utf-16le", Source: charsetFromBOM})
+	case bytes.HasPrefix(body, []byte{0xFE, 0xFF}):
+		return transcode(body, unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM), model.DetectedCharset{Name: "utf-16be", Source: charsetFromBOM})
+	}
+
+	// 3. Content-Type 中的 charset 参数
+	if _, params, err := mime.ParseMediaType(contentType); err == nil {
+		if label := params["charset"]; label != "" {
+			if enc, name, ok := lookupCharset(label); ok {
+				return transcode(body, enc, model.DetectedCharset{Name: name, Source: charsetFromContentType})
+			}
+		}
+	}
+
+	// 4. XML 声明或 HTML <meta>（只看前 1KB）
+	head := body
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+	if m := declaredCharsetRe.FindSubmatch(head); m != nil {
+		if enc, name, ok := lookupCharset(string(m[1])); ok {
+			return transcode(body, enc, model.DetectedCharset{Name: name, Source: charsetFromDocument})
+		}
+	}
+
+	// 5. 没有任何声明且不是合法 UTF-8：国内来源绝大多数是 GBK/GB2312，按其超集 GB18030 解码
+	if !utf8.Valid(body) {
+		enc, name, _ := lookupCharset("gb18030")
+		return transcode(body, enc, model.DetectedCharset{Name: name, Source: charsetFromGuess})
+	}
+
+	return body, model.DetectedCharset{Name: "utf-8", Source: charsetFromDefault}, nil
+}
+
+// lookupCharset 按 WHATWG 编码标签查找编码（gb2312 会映射为 gbk 等），返回规范化名称
+func lookupCharset(label string) (encoding.Encoding, string, bool) {
+	enc, err := htmlindex.Get(label)
+	if err != nil {
+		return nil, "", false
+	}
+	name, err := htmlindex.Name(enc)
+	if err != nil {
+		return nil, "", false
+	}
+	return enc, name, true
+}
+
+// transcode 将 body 从 enc 转换为 UTF-8
+func transcode(body []byte, enc encoding.Encoding, detected model.DetectedCharset) ([]byte, model.DetectedCharset, error) {
+	if detected.Name == "utf-8" {
+		return stripUTF8BOM(body), detected, nil
+	}
+
+	out, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return nil, detected, fmt.Errorf("transcode from %s: %w", detected.Name, err)
+	}
+	return out, detected, nil
+}
+
+func stripUTF8BOM(body []byte) []byte {
+	return bytes.TrimPrefix(body, []byte{0xEF, 0xBB, 0xBF})
+}
@@ -0,0 +1,164 @@
+package processor
+
+import (
+	"api-fetch/internal/api_fetch/model"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+// 内容未变、未保存的原因
+const (
+	UnchangedNotModified = "not_modified" // 条件请求返回 304
+	UnchangedSameHash    = "same_hash"    // 提取数据与上次保存的相同
+)
+
+// errNotModified 条件请求返回 304，视为成功但不保存
+var errNotModified = errors.New("not modified")
+
+// conditional 一次抓取的条件请求状态
+type conditional struct {
+	key  string
+	date string            // 本次写入的分表日期
+	prev *model.FetchState // 上次保存的状态，可为空
+
+	// 本次首页响应的校验值
+	etag         string
+	lastModified string
+}
+
+// loadConditional 读取 API 上次保存的状态；always_store 或读取失败时返回 nil，按普通请求处理
+func (p *Processor) loadConditional(ctx context.Context, api *model.APIInfo, now time.Time) *conditional {
+	if api.AlwaysStore || p.Stores == nil || p.Stores.FetchState == nil {
+		return nil
+	}
+	c := &conditional{
+		key:  fetchStateKey(api),
+		date: now.In(shanghaiLocation()).Format("2006-01-02"),
+	}
+	var prev model.FetchState
+	err := p.Stores.FetchState.FindOne(ctx, bson.M{"_id": c.key}).Decode(&prev)
+	switch {
+	case err == nil:
+		c.prev = &prev
+	case !errors.Is(err, mongo.ErrNoDocuments):
+		p.Log.Warn("Failed to load fetch state",
+			zap.String("source", api.Source),
+			zap.String("category", api.Category),
+			zap.Error(err),
+		)
+		return nil
+	}
+	return c
+}
+
+func fetchStateKey(api *model.APIInfo) string {
+	if api.ID != "" {
+		return api.ID
+	}
+	return processorKey(api.Source, api.Category, api.InfoType)
+}
+
+// sameDay 上次保存的是否为本次的分表；跨天后无论内容是否变化都保存一份，保证每天的分表都有数据
+func (c *conditional) sameDay() bool {
+	return c != nil && c.prev != nil && c.prev.Date == c.date
+}
+
+// apply 首页请求附加 If-None-Match / If-Modified-Since；分页接口只看首页无法判断整体是否变化，不发送
+func (c *conditional) apply(req *http.Request, api *model.APIInfo, pageReq *pageRequest) bool {
+	if !c.sameDay() || pageReq.index != 0 || api.Pagination != nil {
+		return false
+	}
+	sent := false
+	if c.prev.ETag != "" {
+		req.Header.Set("If-None-Match", c.prev.ETag)
+		sent = true
+	}
+	if c.prev.LastModified != "" {
+		req.Header.Set("If-Modified-Since", c.prev.LastModified)
+		sent = true
+	}
+	return sent
+}
+
+// capture 记录首页响应的校验值，保存成功或内容未变时写入 fetch_state
+func (c *conditional) capture(resp *http.Response, pageReq *pageRequest) {
+	if c == nil || pageReq.index != 0 {
+		return
+	}
+	c.etag = resp.Header.Get("ETag")
+	c.lastModified = resp.Header.Get("Last-Modified")
+}
+
+// unchanged 提取数据的哈希与上次保存的相同
+func (c *conditional) unchanged(hash string) bool {
+	return c.sameDay() && c.prev.ContentHash == hash
+}
+
+// contentHash 各页提取数据的 SHA-256；encoding/json 按键排序输出 map，结果与字段顺序无关
+func contentHash(pages []fetchedPage) (string, error) {
+	h := sha256.New()
+	enc := json.NewEncoder(h)
+	for _, page := range pages {
+		if err := enc.Encode(page.data); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// saveFetchState 保存成功后记录校验值与内容哈希；失败只记日志，下次按普通请求处理
+func (p *Processor) saveFetchState(ctx context.Context, api *model.APIInfo, c *conditional, hash string) {
+	if c == nil {
+		return
+	}
+	now := time.Now().UTC()
+	state := model.FetchState{
+		ID:           c.key,
+		ETag:         c.etag,
+		LastModified: c.lastModified,
+		ContentHash:  hash,
+		Date:         c.date,
+		StoredAt:     now,
+		CheckedAt:    now,
+	}
+	if _, err := p.Stores.FetchState.ReplaceOne(ctx, bson.M{"_id": c.key}, state, options.Replace().SetUpsert(true)); err != nil {
+		p.Log.Warn("Failed to save fetch state",
+			zap.String("source", api.Source),
+			zap.String("category", api.Category),
+			zap.Error(err),
+		)
+	}
+}
+
+// touchFetchState 内容未变时更新确认时间与本次响应的校验值，内容哈希与保存时间不变；
+// 304 响应未带校验值时沿用上次的，same_hash 时以本次响应为准
+func (p *Processor) touchFetchState(ctx context.Context, c *conditional, reason string) {
+	if c == nil {
+		return
+	}
+	set := bson.M{"checked_at": time.Now().UTC()}
+	unset := bson.M{}
+	for field, value := range map[string]string{"etag": c.etag, "last_modified": c.lastModified} {
+		switch {
+		case value != "":
+			set[field] = value
+		case reason == UnchangedSameHash:
+			unset[field] = ""
+		}
+	}
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	_, _ = p.Stores.FetchState.UpdateOne(ctx, bson.M{"_id": c.key}, update)
+}
@@ -1,13 +1,17 @@
 package processor
 
 import (
+	"api-fetch/internal/api_fetch/alert"
 	"api-fetch/internal/api_fetch/helper"
+	"api-fetch/internal/api_fetch/metrics"
 	"api-fetch/internal/api_fetch/model"
 	"context"
 	"fmt"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.uber.org/zap"
+	"strconv"
+	"sync"
 	"time"
 )
 
@@ -17,15 +21,38 @@ type DataProcessorConfig struct {
 	Category string `json:"category"`
 	InfoType string `json:"info_type"`
 	Enabled  bool   `json:"enabled"`
+
+	ItemsField  string   `json:"items_field,omitempty"`  // ProcessedData.Data 中条目数组的字段名，默认 "articles"
+	DedupFields []string `json:"dedup_fields,omitempty"` // 条目去重键字段，默认 ["articleID"]
+}
+
+// itemsField 条目数组的字段名
+func (c DataProcessorConfig) itemsField() string {
+	if c.ItemsField == "" {
+		return defaultOutputField
+	}
+	return c.ItemsField
+}
+
+// Key 处理函数注册键：source_category_infotype
+func (c DataProcessorConfig) Key() string {
+	return processorKey(c.Source, c.Category, c.InfoType)
 }
 
 // DataProcessor 数据处理器
 type DataProcessor struct {
-	Log    *zap.Logger
-	Stores *helper.Stores
+	Log     *zap.Logger
+	Stores  *helper.Stores
+	Alerts  *alert.Manager // 可为空
+	Anomaly AnomalyConfig
 
-	// 处理函数映射
+	// 处理函数映射：内置处理函数
 	processors map[string]DataProcessorFunc
+
+	// 从 transform_rules 加载的处理函数，优先于同名内置处理函数
+	mu             sync.RWMutex
+	ruleProcessors map[string]DataProcessorFunc
+	scripts        map[string]*compiledScript // 已编译的转换脚本，按 processorKey 缓存
 }
 
 // DataProcessorFunc 处理函数类型
@@ -55,30 +82,42 @@ func (dp *DataProcessor) registerProcessors() {
 	dp.processors["澎湃_general_daily"] = dp.processPengpaiDaily
 }
 
-// Run 启动数据处理器
-func (dp *DataProcessor) Run(ctx context.Context, configs []DataProcessorConfig) {
-	for _, cfg := range configs {
-		if !cfg.Enabled {
-			continue
-		}
-		// 为每个配置启动一个独立协程，协程内部只负责 **一次** 处理（外部计时器决定何时再次调用）。
-		go func(c DataProcessorConfig) {
-			// 这里不再使用 ticker，直接调用一次
-			dp.processData(ctx, c)
-		}(cfg)
+// lookupProcessor 查找处理函数，DB 规则优先于内置处理函数
+func (dp *DataProcessor) lookupProcessor(key string) (DataProcessorFunc, bool) {
+	dp.mu.RLock()
+	fn, ok := dp.ruleProcessors[key]
+	dp.mu.RUnlock()
+	if ok {
+		return fn, true
 	}
+	fn, ok = dp.processors[key]
+	return fn, ok
+}
+
+// ProcessStats 一次后处理的统计
+type ProcessStats struct {
+	Processed int `json:"processed"`
+	Failed    int `json:"failed"`
+	Anomalies int `json:"anomalies"` // 输出条目数异常的文档数
+}
+
+// ProcessDate 同步处理指定日期分表中尚未处理的数据，异常记录关联 runID
+func (dp *DataProcessor) ProcessDate(ctx context.Context, config DataProcessorConfig, date time.Time, runID string) (ProcessStats, error) {
+	return dp.processData(ctx, config, date, runID)
 }
 
-// processData 处理数据
-func (dp *DataProcessor) processData(ctx context.Context, config DataProcessorConfig) {
-	processorKey := fmt.Sprintf("%s_%s_%s", config.Source, config.Category, config.InfoType)
+// processData 处理 date 当天分表中的数据
+func (dp *DataProcessor) processData(ctx context.Context, config DataProcessorConfig, date time.Time, runID string) (ProcessStats, error) {
+	var stats ProcessStats
+	var lastErr error
+	key := config.Key()
 
-	processor, exists := dp.processors[processorKey]
+	processor, exists := dp.lookupProcessor(key)
 	if !exists {
 		dp.Log.Warn("No processor found for config",
-			zap.String("processorKey", processorKey),
+			zap.String("processorKey", key),
 		)
-		return
+		return stats, fmt.Errorf("no processor found for %s", key)
 	}
 
 	// 查询未处理的数据
@@ -89,9 +128,8 @@ func (dp *DataProcessor) processData(ctx context.Context, config DataProcessorCo
 		"processed": false,
 	}
 
-	// 获取今天的集合名
-	today := time.Now()
-	collName := helper.RawDataCollName(today)
+	// 获取当天的集合名
+	collName := helper.RawDataCollName(date)
 	collection := dp.Stores.DB.Collection(collName)
 
 	cursor, err := collection.Find(ctx, filter)
@@ -101,15 +139,19 @@ func (dp *DataProcessor) processData(ctx context.Context, config DataProcessorCo
 			zap.Any("filter", filter),
 			zap.Error(err),
 		)
-		return
+		return stats, err
 	}
 	defer cursor.Close(ctx)
 
-	processedCount := 0
+	// linked 分页的各页属于同一次抓取，跨页重复的条目只累加一次 seen_count
+	groups := make(map[string]map[string]struct{})
+
 	for cursor.Next(ctx) {
 		var doc model.CrawlResult
 		if err := cursor.Decode(&doc); err != nil {
 			dp.Log.Error("Failed to decode document", zap.Error(err))
+			stats.Failed++
+			lastErr = err
 			continue
 		}
 
@@ -120,15 +162,42 @@ func (dp *DataProcessor) processData(ctx context.Context, config DataProcessorCo
 				zap.String("docId", doc.ID.Hex()),
 				zap.Error(err),
 			)
+			stats.Failed++
+			lastErr = err
 			continue
 		}
 
+		// 输出条目数与基线比较
+		if items, ok := asSlice(processedData.Data[config.itemsField()]); ok {
+			sample := itemSample{
+				kind:     ItemKindProcess,
+				key:      key,
+				runID:    runID,
+				source:   config.Source,
+				category: config.Category,
+				infoType: config.InfoType,
+				count:    len(items),
+			}
+			if dp.anomalies().check(ctx, sample) != nil {
+				stats.Anomalies++
+			}
+		}
+
 		// 保存处理后的数据
-		if err := dp.saveProcessedData(ctx, processedData); err != nil {
+		var seen map[string]struct{}
+		if doc.PageGroup != "" {
+			if groups[doc.PageGroup] == nil {
+				groups[doc.PageGroup] = make(map[string]struct{})
+			}
+			seen = groups[doc.PageGroup]
+		}
+		if err := dp.saveProcessedData(ctx, processedData, config, seen); err != nil {
 			dp.Log.Error("Failed to save processed data",
 				zap.String("docId", doc.ID.Hex()),
 				zap.Error(err),
 			)
+			stats.Failed++
+			lastErr = err
 			continue
 		}
 
@@ -138,22 +207,66 @@ func (dp *DataProcessor) processData(ctx context.Context, config DataProcessorCo
 				zap.String("docId", doc.ID.Hex()),
 				zap.Error(err),
 			)
+			stats.Failed++
+			lastErr = err
 			continue
 		}
 
-		processedCount++
+		stats.Processed++
 	}
 
-	if processedCount > 0 {
+	metrics.DocumentsProcessed(config.Source, config.Category, config.InfoType, stats.Processed, stats.Failed)
+	if stats.Failed > 0 {
+		dp.notifyFailure(config, date, stats, lastErr)
+	}
+	if stats.Processed > 0 {
 		dp.Log.Info("Data processing completed",
-			zap.String("processorKey", processorKey),
-			zap.Int("processedCount", processedCount),
+			zap.String("processorKey", key),
+			zap.Int("processedCount", stats.Processed),
 		)
 	}
+	return stats, cursor.Err()
+}
+
+// notifyFailure 后处理有文档失败时告警
+func (dp *DataProcessor) notifyFailure(config DataProcessorConfig, date time.Time, stats ProcessStats, lastErr error) {
+	msg := "see logs for details"
+	if lastErr != nil {
+		msg = lastErr.Error()
+	}
+	dp.Alerts.Notify(alert.Alert{
+		Event:    alert.EventProcessorFailure,
+		Source:   config.Source,
+		Category: config.Category,
+		InfoType: config.InfoType,
+		Title:    fmt.Sprintf("%s post-processing failed for %d documents", config.Key(), stats.Failed),
+		Message:  msg,
+		Fields: map[string]string{
+			"collection": helper.RawDataCollName(date),
+			"processed":  strconv.Itoa(stats.Processed),
+			"failed":     strconv.Itoa(stats.Failed),
+		},
+	})
 }
 
 // saveProcessedData 保存处理后的数据
-func (dp *DataProcessor) saveProcessedData(ctx context.Context, data *model.ProcessedData) error {
+// 含条目数组时按去重键 upsert 到 articles 集合，否则整份写入 processed_data；seen 为同一次抓取中已写入的去重键，可为空
+func (dp *DataProcessor) saveProcessedData(ctx context.Context, data *model.ProcessedData, config DataProcessorConfig, seen map[string]struct{}) error {
+	if items, ok := asSlice(data.Data[config.itemsField()]); ok {
+		inserted, updated, err := dp.upsertArticles(ctx, data, items, config.DedupFields, seen)
+		if err != nil {
+			return err
+		}
+		dp.Log.Debug("Articles upserted",
+			zap.String("source", data.Source),
+			zap.String("rawDocId", data.RawDocID),
+			zap.Int("items", len(items)),
+			zap.Int64("inserted", inserted),
+			zap.Int64("updated", updated),
+		)
+		return nil
+	}
+
 	// 使用专门的处理后数据集合
 	collection := dp.Stores.DB.Collection("processed_data")
 
@@ -0,0 +1,124 @@
+package processor
+
+import (
+	"api-fetch/internal/api_fetch/model"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+// defaultDedupFields 未配置去重字段时使用的默认字段
+var defaultDedupFields = []string{"articleID"}
+
+// dedupKey 计算条目的去重键：
+// 单个字段直接使用其值，多个字段取哈希；字段缺失时退化为 url+title 的哈希
+func dedupKey(item map[string]interface{}, fields []string) (string, bool) {
+	if len(fields) == 0 {
+		fields = defaultDedupFields
+	}
+
+	values := make([]string, 0, len(fields))
+	for _, field := range fields {
+		v, ok := lookupPath(item, field)
+		if !ok || isEmpty(v) {
+			values = nil
+			break
+		}
+		values = append(values, fmt.Sprintf("%v", v))
+	}
+	if len(values) == 1 {
+		return values[0], true
+	}
+	if len(values) > 1 {
+		return hashKey(values...), true
+	}
+
+	// 兜底：链接 + 标题
+	link, _ := firstNonEmpty(item, []string{"origin_url", "url", "link"})
+	title, _ := firstNonEmpty(item, []string{"title", "name"})
+	if link == nil && title == nil {
+		return "", false
+	}
+	return hashKey(fmt.Sprintf("%v", link), fmt.Sprintf("%v", title)), true
+}
+
+func hashKey(parts ...string) string {
+	sum := sha1.Sum([]byte(strings.Join(parts, "\n")))
+	return "sha1:" + hex.EncodeToString(sum[:])
+}
+
+// upsertArticles 将条目按去重键写入 articles 集合：
+// 新条目记录 first_seen，已存在的更新内容与 last_seen，并累加 seen_count；
+// seen 记录同一次抓取中已写入的去重键（linked 分页跨页共享），为空时只在本份数据内去重
+func (dp *DataProcessor) upsertArticles(ctx context.Context, data *model.ProcessedData, items []interface{}, fields []string, seen map[string]struct{}) (inserted, updated int64, err error) {
+	now := time.Now().UTC()
+	if seen == nil {
+		seen = make(map[string]struct{}, len(items))
+	}
+	var models []mongo.WriteModel
+	skipped := 0
+
+	for _, raw := range items {
+		item, ok := asMap(raw)
+		if !ok {
+			skipped++
+			continue
+		}
+		key, ok := dedupKey(item, fields)
+		if !ok {
+			skipped++
+			continue
+		}
+		// 同一次抓取中的重复条目（如同时出现在多个子列表或多页）只计一次
+		if _, dup := seen[key]; dup {
+			continue
+		}
+		seen[key] = struct{}{}
+
+		filter := bson.M{
+			"source":    data.Source,
+			"category":  data.Category,
+			"info_type": data.InfoType,
+			"dedup_key": key,
+		}
+		update := bson.M{
+			"$setOnInsert": bson.M{
+				"first_seen": now,
+				"first_date": data.Date,
+			},
+			"$set": bson.M{
+				"data":       item,
+				"last_seen":  now,
+				"last_date":  data.Date,
+				"raw_doc_id": data.RawDocID,
+			},
+			"$inc": bson.M{"seen_count": 1},
+		}
+		models = append(models, mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update).SetUpsert(true))
+	}
+
+	if skipped > 0 {
+		dp.Log.Warn("Items without dedup key skipped",
+			zap.String("source", data.Source),
+			zap.String("rawDocId", data.RawDocID),
+			zap.Int("skipped", skipped),
+		)
+	}
+	if len(models) == 0 {
+		return 0, 0, nil
+	}
+
+	res, err := dp.Stores.Articles.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		return 0, 0, err
+	}
+	return res.UpsertedCount, res.MatchedCount, nil
+}
@@ -0,0 +1,235 @@
+package processor
+
+import (
+	"api-fetch/internal/api_fetch/model"
+	"context"
+	"io"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// dryRunBodyExcerpt 试运行结果中保留的响应体长度
+const dryRunBodyExcerpt = 4096
+
+// 试运行中失败的步骤
+const (
+	stageRequest   = "request"
+	stageFetch     = "fetch"
+	stageDecode    = "decode"
+	stageParse     = "parse"
+	stageRequired  = "required"
+	stageExtract   = "extract"
+	stageTransform = "transform"
+)
+
+// 请求头中需要脱敏的字段
+var redactedHeaders = []string{"Authorization", "Cookie", "Proxy-Authorization"}
+
+// DryRunRequest 实际发出的请求
+type DryRunRequest struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Headers http.Header `json:"headers"`
+	Body    string      `json:"body,omitempty"`
+}
+
+// DryRunResult 试运行结果：只抓取第一页，不写库
+type DryRunResult struct {
+	Request        *DryRunRequest         `json:"request,omitempty"`
+	Status         int                    `json:"status,omitempty"`
+	Headers        http.Header            `json:"headers,omitempty"`
+	BodySize       int                    `json:"body_size"`
+	BodyExcerpt    string                 `json:"body_excerpt,omitempty"`
+	Truncated      bool                   `json:"truncated,omitempty"`
+	Charset        *model.DetectedCharset `json:"charset,omitempty"`
+	RequiredChecks []RequiredCheck        `json:"required_checks"`
+	Strategy       string                 `json:"extraction_strategy,omitempty"`
+	Data           bson.M                 `json:"data,omitempty"`
+	ItemCount      int                    `json:"item_count"`
+	ProcessorKey   string                 `json:"processor_key"`
+	HasProcessor   bool                   `json:"has_processor"`
+	Transformed    map[string]any         `json:"transformed,omitempty"`
+	Stage          string                 `json:"failed_stage,omitempty"` // 失败的步骤，成功时为空
+	Error          string                 `json:"error,omitempty"`
+	DurationMS     int64                  `json:"duration_ms"`
+}
+
+// DryRun 按正式抓取流程构建请求、解析校验、提取数据，并调用已注册的后处理函数，
+// 但不写入 rawdata_* 与 articles；dp 为空时跳过后处理。
+// resolveSecrets 为 false 时（试运行请求体中的配置）secret 渲染为空串，也不使用已缓存的认证令牌，
+// 避免密钥被发往任意地址
+func (p *Processor) DryRun(ctx context.Context, api *model.APIInfo, dp *DataProcessor, resolveSecrets bool) *DryRunResult {
+	// 试运行不触发告警
+	dry := *p
+	dry.Alerts = nil
+	if !resolveSecrets {
+		dry.Auth = NewAuthenticators(p.HTTPClient)
+	}
+	p = &dry
+
+	start := time.Now()
+	res := &DryRunResult{
+		RequiredChecks: []RequiredCheck{},
+		ItemCount:      -1,
+		ProcessorKey:   processorKey(api.Source, api.Category, api.InfoType),
+	}
+	defer func() { res.DurationMS = time.Since(start).Milliseconds() }()
+
+	var secrets *secretSet
+	fail := func(stage string, err error) *DryRunResult {
+		res.Stage = stage
+		res.Error = secrets.mask(err.Error())
+		return res
+	}
+
+	if err := validatePagination(api.Pagination); err != nil {
+		return fail(stageRequest, err)
+	}
+
+	// 1. 构建请求（仅第一页）
+	pageReq := newPager(api.Pagination).first()
+	tmplData := newTemplateData(api, start, 1, pageReq)
+	tmplData.secrets.stub = !resolveSecrets
+	secrets = tmplData.secrets
+	req, err := p.buildHTTPRequest(ctx, api, pageReq, tmplData)
+	if err != nil {
+		return fail(stageRequest, err)
+	}
+	authErr := p.applyAuth(ctx, api, req, tmplData)
+	res.Request = describeRequest(req, tmplData.secrets)
+	redactAuth(res.Request, api.Auth)
+	if authErr != nil {
+		return fail(stageRequest, authErr)
+	}
+
+	// 2. 执行请求，同样受 host 限速约束
+	if err := p.Limiter.waitHost(ctx, req.URL.Hostname(), nil); err != nil {
+		return fail(stageFetch, err)
+	}
+	resp, err := p.HTTPClient.Do(req)
+	if err != nil {
+		return fail(stageFetch, err)
+	}
+	defer resp.Body.Close()
+	res.Status = resp.StatusCode
+	res.Headers = resp.Header
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fail(stageFetch, err)
+	}
+
+	// 3. 编码检测
+	body, charset, err := decodeBody(body, resp.Header.Get("Content-Type"), api)
+	res.BodySize = len(body)
+	res.BodyExcerpt, res.Truncated = excerpt(body, dryRunBodyExcerpt)
+	if err != nil {
+		return fail(stageDecode, err)
+	}
+	res.Charset = &charset
+
+	// 4. 解析并校验 Required；校验失败时不带 Required 重新解析，以返回每条检查的明细
+	parsedObj, err := p.parseAndValidate(body, api, 1, req.URL)
+	if err != nil {
+		if parsed, perr := p.parseOnly(body, api, req); perr == nil {
+			res.RequiredChecks = evalRequired(parsed, api.Required)
+			return fail(stageRequired, err)
+		}
+		return fail(stageParse, err)
+	}
+	res.RequiredChecks = evalRequired(parsedObj, api.Required)
+
+	// 5. 提取数据
+	data, strategy, err := p.extractData(parsedObj, api, 1)
+	if err != nil {
+		return fail(stageExtract, err)
+	}
+	res.Data = data
+	res.Strategy = strategy
+	res.ItemCount = countItems(data)
+
+	// 6. 后处理
+	if dp == nil {
+		return res
+	}
+	fn, ok := dp.lookupProcessor(res.ProcessorKey)
+	res.HasProcessor = ok
+	if !ok {
+		return res
+	}
+	doc := &model.CrawlResult{
+		ID:        primitive.NewObjectID(),
+		Date:      start.In(shanghaiLocation()).Format("2006-01-02"),
+		Source:    api.Source,
+		Category:  api.Category,
+		InfoType:  api.InfoType,
+		Data:      data,
+		CreatedAt: start.UTC(),
+		Encoding:  &charset,
+	}
+	processed, err := fn(ctx, doc)
+	if err != nil {
+		return fail(stageTransform, err)
+	}
+	res.Transformed = processed.Data
+	return res
+}
+
+// parseOnly 只解析不校验 Required，用于在校验失败时展示检查明细
+func (p *Processor) parseOnly(body []byte, api *model.APIInfo, req *http.Request) (map[string]any, error) {
+	noRequired := *api
+	noRequired.Required = nil
+	return p.parseAndValidate(body, &noRequired, 1, req.URL)
+}
+
+// describeRequest 记录请求内容，敏感请求头脱敏
+func describeRequest(req *http.Request, secrets *secretSet) *DryRunRequest {
+	out := &DryRunRequest{
+		Method:  req.Method,
+		URL:     secrets.mask(req.URL.String()),
+		Headers: req.Header.Clone(),
+	}
+	for name, values := range out.Headers {
+		for i, v := range values {
+			out.Headers[name][i] = secrets.mask(v)
+		}
+	}
+	for _, h := range redactedHeaders {
+		if out.Headers.Get(h) != "" {
+			out.Headers.Set(h, "***")
+		}
+	}
+	if req.GetBody != nil {
+		if rc, err := req.GetBody(); err == nil {
+			b, _ := io.ReadAll(rc)
+			_ = rc.Close()
+			out.Body, _ = excerpt([]byte(secrets.mask(string(b))), dryRunBodyExcerpt)
+		}
+	}
+	return out
+}
+
+// excerpt 截取前 n 字节，不截断多字节字符
+func excerpt(b []byte, n int) (string, bool) {
+	if len(b) <= n {
+		return string(b), false
+	}
+	cut := n
+	for cut > 0 && !utf8.RuneStart(b[cut]) {
+		cut--
+	}
+	return string(b[:cut]), true
+}
+
+// shanghaiLocation 与 saveToDatabase 一致，使用 Asia/Shanghai 生成 date 字段
+func shanghaiLocation() *time.Location {
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
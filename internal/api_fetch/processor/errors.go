@@ -0,0 +1,109 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 抓取错误分类
+const (
+	ErrClassConfig     = "config"       // 配置错误（分页、请求方式等）
+	ErrClassNetwork    = "network"      // 连接失败、读取响应失败
+	ErrClassTimeout    = "timeout"      // 请求超时
+	ErrClassHTTPStatus = "http_status"  // 非 2xx 响应且无法解析
+	ErrClassDecode     = "decode"       // 编码转换失败
+	ErrClassParse      = "parse"        // 响应体无法解析
+	ErrClassRequired   = "required"     // Required 校验不通过
+	ErrClassExtract    = "extract"      // 找不到数据字段
+	ErrClassStorage    = "storage"      // 写库失败
+	ErrClassCanceled   = "canceled"     // 服务停止
+	ErrClassCircuit    = "circuit_open" // 熔断中，未发出请求
+)
+
+// errCircuitOpen 熔断期间跳过的抓取
+var errCircuitOpen = errors.New("skipped: circuit open")
+
+// fetchError 带分类的抓取错误
+type fetchError struct {
+	class      string
+	err        error
+	status     int           // 响应状态码，未收到响应时为 0
+	retryAfter time.Duration // 响应头 Retry-After 要求的等待时间
+}
+
+func (e *fetchError) Error() string { return e.err.Error() }
+func (e *fetchError) Unwrap() error { return e.err }
+
+// classify 给错误标记分类，已有分类的保持不变
+func classify(class string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var fe *fetchError
+	if errors.As(err, &fe) {
+		return err
+	}
+	return &fetchError{class: class, err: err}
+}
+
+// ErrorClass 返回错误分类；超时与取消优先于标记的分类
+func ErrorClass(err error) string {
+	if err == nil {
+		return ""
+	}
+	if errors.Is(err, context.Canceled) {
+		return ErrClassCanceled
+	}
+	var ne net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &ne) && ne.Timeout()) {
+		return ErrClassTimeout
+	}
+	var fe *fetchError
+	if errors.As(err, &fe) {
+		return fe.class
+	}
+	return ErrClassNetwork
+}
+
+// withResponse 给错误附加响应状态码与 Retry-After，未分类的错误按 class 分类
+func withResponse(class string, err error, resp *http.Response) error {
+	err = classify(class, err)
+	var fe *fetchError
+	if errors.As(err, &fe) {
+		fe.status = resp.StatusCode
+		fe.retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	}
+	return err
+}
+
+// responseInfo 错误附带的响应状态码与 Retry-After
+func responseInfo(err error) (status int, retryAfter time.Duration) {
+	var fe *fetchError
+	if errors.As(err, &fe) {
+		return fe.status, fe.retryAfter
+	}
+	return 0, 0
+}
+
+// parseRetryAfter 解析 Retry-After：秒数或 HTTP 日期，无法解析或已过期时返回 0
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs <= 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
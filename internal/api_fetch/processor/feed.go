@@ -0,0 +1,285 @@
+package processor
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// RSS 2.0 文档结构
+type rssDocument struct {
+	XMLName xml.Name `xml:"rss"`
+	Channel struct {
+		Title       string    `xml:"title"`
+		Links       []xmlLink `xml:"link"`
+		Description string    `xml:"description"`
+		Items       []struct {
+			Title       string    `xml:"title"`
+			Links       []xmlLink `xml:"link"`
+			Description string    `xml:"description"`
+			Content     string    `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+			PubDate     string    `xml:"pubDate"`
+			DCDate      string    `xml:"http://purl.org/dc/elements/1.1/ date"`
+			GUID        string    `xml:"guid"`
+			Author      string    `xml:"author"`
+			Creator     string    `xml:"http://purl.org/dc/elements/1.1/ creator"`
+			Categories  []string  `xml:"category"`
+		} `xml:"item"`
+	} `xml:"channel"`
+}
+
+// Atom 文档结构
+type atomDocument struct {
+	XMLName  xml.Name  `xml:"feed"`
+	Title    string    `xml:"title"`
+	Subtitle string    `xml:"subtitle"`
+	Links    []xmlLink `xml:"link"`
+	Entries  []struct {
+		ID        string    `xml:"id"`
+		Title     string    `xml:"title"`
+		Links     []xmlLink `xml:"link"`
+		Summary   string    `xml:"summary"`
+		Content   string    `xml:"content"`
+		Published string    `xml:"published"`
+		Updated   string    `xml:"updated"`
+		Authors   []struct {
+			Name string `xml:"name"`
+		} `xml:"author"`
+		Categories []struct {
+			Term string `xml:"term,attr"`
+		} `xml:"category"`
+	} `xml:"entry"`
+}
+
+// xmlLink 兼容 RSS 的 <link>url</link> 与 Atom 的 <link href="url" rel="alternate"/>
+type xmlLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Text string `xml:",chardata"`
+}
+
+// pickLink 优先取 rel=alternate（或无 rel）的链接
+func pickLink(links []xmlLink) string {
+	fallback := ""
+	for _, l := range links {
+		u := strings.TrimSpace(l.Text)
+		if u == "" {
+			u = strings.TrimSpace(l.Href)
+		}
+		if u == "" {
+			continue
+		}
+		if l.Rel == "" || l.Rel == "alternate" {
+			return u
+		}
+		if fallback == "" {
+			fallback = u
+		}
+	}
+	return fallback
+}
+
+// newXMLDecoder 创建 XML 解码器，容忍 feed 中常见的 HTML 实体（如 &nbsp;）
+// 注意不能启用 HTMLAutoClose，否则 RSS 的 <link> 会被当作空元素
+func newXMLDecoder(body []byte) *xml.Decoder {
+	dec := xml.NewDecoder(bytes.NewReader(body))
+	dec.Strict = false
+	dec.Entity = xml.HTMLEntity
+	// body 已由 decodeBody 转为 UTF-8，忽略声明中的 encoding="GBK" 等
+	dec.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	return dec
+}
+
+// parseRSS 将 RSS 2.0 解析为 {"feed": {...}, "items": [...]}
+func parseRSS(body []byte) (map[string]any, error) {
+	var doc rssDocument
+	if err := newXMLDecoder(body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid rss: %w", err)
+	}
+
+	items := make([]any, 0, len(doc.Channel.Items))
+	for _, it := range doc.Channel.Items {
+		author := it.Author
+		if author == "" {
+			author = it.Creator
+		}
+		published := it.PubDate
+		if published == "" {
+			published = it.DCDate
+		}
+		categories := make([]any, 0, len(it.Categories))
+		for _, c := range it.Categories {
+			categories = append(categories, strings.TrimSpace(c))
+		}
+		items = append(items, feedItem(it.Title, pickLink(it.Links), it.Description, it.Content,
+			published, it.GUID, author, categories))
+	}
+
+	return map[string]any{
+		"feed": map[string]any{
+			"title":       strings.TrimSpace(doc.Channel.Title),
+			"link":        pickLink(doc.Channel.Links),
+			"description": strings.TrimSpace(doc.Channel.Description),
+			"format":      FormatRSS,
+		},
+		"items": items,
+	}, nil
+}
+
+// parseAtom 将 Atom 解析为 {"feed": {...}, "items": [...]}
+func parseAtom(body []byte) (map[string]any, error) {
+	var doc atomDocument
+	if err := newXMLDecoder(body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid atom: %w", err)
+	}
+
+	items := make([]any, 0, len(doc.Entries))
+	for _, e := range doc.Entries {
+		var authors []string
+		for _, a := range e.Authors {
+			if name := strings.TrimSpace(a.Name); name != "" {
+				authors = append(authors, name)
+			}
+		}
+		categories := make([]any, 0, len(e.Categories))
+		for _, c := range e.Categories {
+			categories = append(categories, c.Term)
+		}
+		published := e.Published
+		if published == "" {
+			published = e.Updated
+		}
+		items = append(items, feedItem(e.Title, pickLink(e.Links), e.Summary, e.Content,
+			published, e.ID, strings.Join(authors, ", "), categories))
+	}
+
+	return map[string]any{
+		"feed": map[string]any{
+			"title":       strings.TrimSpace(doc.Title),
+			"link":        pickLink(doc.Links),
+			"description": strings.TrimSpace(doc.Subtitle),
+			"format":      FormatAtom,
+		},
+		"items": items,
+	}, nil
+}
+
+// feedItem 统一的条目结构，RSS 与 Atom 字段名一致，方便后处理复用
+func feedItem(title, link, summary, content, published, guid, author string, categories []any) map[string]any {
+	item := map[string]any{
+		"title":      strings.TrimSpace(title),
+		"url":        link,
+		"summary":    strings.TrimSpace(summary),
+		"guid":       strings.TrimSpace(guid),
+		"author":     strings.TrimSpace(author),
+		"categories": categories,
+	}
+	if content = strings.TrimSpace(content); content != "" {
+		item["content"] = content
+	}
+	if published = strings.TrimSpace(published); published != "" {
+		item["published"] = published
+		if t, ok := parseFeedTime(published); ok {
+			item["published_at"] = t
+		}
+	}
+	if item["guid"] == "" {
+		item["guid"] = link
+	}
+	return item
+}
+
+// feedTimeLayouts RSS/Atom 中常见的时间格式
+var feedTimeLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC3339,
+	time.RFC3339Nano,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// parseFeedTime 解析条目发布时间，没有时区信息的按上海时间处理
+func parseFeedTime(s string) (time.Time, bool) {
+	shanghai, _ := time.LoadLocation("Asia/Shanghai")
+	for _, layout := range feedTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, shanghai); err == nil {
+			return t.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseXML 将任意 XML 转换为嵌套 map：{根元素名: {...}}
+// 属性以 "@名称" 表示，文本内容在有属性或子元素时以 "#text" 表示，同名子元素合并为数组
+func parseXML(body []byte) (map[string]any, error) {
+	dec := newXMLDecoder(body)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil, fmt.Errorf("invalid xml: no root element")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid xml: %w", err)
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			root, err := decodeXMLElement(dec, start)
+			if err != nil {
+				return nil, fmt.Errorf("invalid xml: %w", err)
+			}
+			return map[string]any{start.Name.Local: root}, nil
+		}
+	}
+}
+
+// decodeXMLElement 递归解码 start 对应的元素
+func decodeXMLElement(dec *xml.Decoder, start xml.StartElement) (any, error) {
+	node := make(map[string]any)
+	for _, attr := range start.Attr {
+		node["@"+attr.Name.Local] = attr.Value
+	}
+
+	var text strings.Builder
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			child, err := decodeXMLElement(dec, t)
+			if err != nil {
+				return nil, err
+			}
+			name := t.Name.Local
+			switch existing := node[name].(type) {
+			case nil:
+				node[name] = child
+			case []any:
+				node[name] = append(existing, child)
+			default:
+				node[name] = []any{existing, child}
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			content := strings.TrimSpace(text.String())
+			if len(node) == 0 {
+				return content, nil
+			}
+			if content != "" {
+				node["#text"] = content
+			}
+			return node, nil
+		}
+	}
+}
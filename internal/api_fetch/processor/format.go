@@ -0,0 +1,80 @@
+package processor
+
+import (
+	"api-fetch/internal/api_fetch/model"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// 支持的响应格式
+const (
+	FormatJSON = "json"
+	FormatXML  = "xml"
+	FormatRSS  = "rss"
+	FormatAtom = "atom"
+	FormatHTML = "html"
+)
+
+// responseFormat 返回 API 的响应格式，未配置时为 json
+func responseFormat(api *model.APIInfo) string {
+	if api.ResponseFormat == "" {
+		return FormatJSON
+	}
+	return strings.ToLower(api.ResponseFormat)
+}
+
+// isNormalizedFormat RSS/Atom/HTML 会被解析为带 items 数组的统一结构，默认直接使用完整解析结果
+func isNormalizedFormat(format string) bool {
+	return format == FormatRSS || format == FormatAtom || format == FormatHTML
+}
+
+// validateResponseFormat 校验响应格式配置
+func validateResponseFormat(format string) error {
+	switch strings.ToLower(format) {
+	case "", FormatJSON, FormatXML, FormatRSS, FormatAtom, FormatHTML:
+		return nil
+	default:
+		return fmt.Errorf("unsupported response format: %s", format)
+	}
+}
+
+// parseAndValidate 按响应格式解析响应体并校验 Required，pageURL 用于补全 HTML 中的相对链接
+func (p *Processor) parseAndValidate(body []byte, api *model.APIInfo, attempt int, pageURL *url.URL) (map[string]any, error) {
+	format := responseFormat(api)
+	if format == FormatJSON {
+		return p.parseAndValidateJSON(body, api, attempt)
+	}
+
+	var parsedObj map[string]any
+	var err error
+	switch format {
+	case FormatRSS:
+		parsedObj, err = parseRSS(body)
+	case FormatAtom:
+		parsedObj, err = parseAtom(body)
+	case FormatXML:
+		parsedObj, err = parseXML(body)
+	case FormatHTML:
+		parsedObj, err = parseHTML(body, api.HTML, pageURL)
+	default:
+		err = validateResponseFormat(format)
+	}
+	if err != nil {
+		p.Log.Warn("Invalid response body",
+			zap.String("format", format),
+			zap.String("source", api.Source),
+			zap.String("category", api.Category),
+			zap.Int("attempt", attempt),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+
+	if err := p.validateRequired(parsedObj, api, attempt); err != nil {
+		return nil, classify(ErrClassRequired, err)
+	}
+	return parsedObj, nil
+}
@@ -0,0 +1,132 @@
+package processor
+
+import (
+	"api-fetch/internal/api_fetch/model"
+	"bytes"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+)
+
+// validateHTMLExtract 校验 HTML 抽取规则，确保所有选择器都能编译
+func validateHTMLExtract(rules *model.HTMLExtract) error {
+	if rules == nil {
+		return fmt.Errorf("html: extraction rules are required")
+	}
+	if rules.List == "" {
+		return fmt.Errorf("html: list selector is required")
+	}
+	if _, err := cascadia.Compile(rules.List); err != nil {
+		return fmt.Errorf("html: invalid list selector %q: %w", rules.List, err)
+	}
+	for name, sel := range htmlFieldSelectors(rules) {
+		if sel.Selector == "" {
+			continue
+		}
+		if _, err := cascadia.Compile(sel.Selector); err != nil {
+			return fmt.Errorf("html: invalid selector for %s %q: %w", name, sel.Selector, err)
+		}
+	}
+	return nil
+}
+
+// htmlFieldSelectors 汇总标准字段与自定义字段的选择器
+func htmlFieldSelectors(rules *model.HTMLExtract) map[string]model.FieldSelector {
+	fields := make(map[string]model.FieldSelector, len(rules.Fields)+4)
+	for name, sel := range rules.Fields {
+		fields[name] = sel
+	}
+	for name, sel := range map[string]*model.FieldSelector{
+		"title":   rules.Title,
+		"url":     rules.URL,
+		"time":    rules.Time,
+		"summary": rules.Summary,
+	} {
+		if sel != nil {
+			fields[name] = *sel
+		}
+	}
+	return fields
+}
+
+// parseHTML 按抽取规则将 HTML 页面转换为 {"page": {...}, "items": [...]}
+func parseHTML(body []byte, rules *model.HTMLExtract, pageURL *url.URL) (map[string]any, error) {
+	if err := validateHTMLExtract(rules); err != nil {
+		return nil, err
+	}
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("invalid html: %w", err)
+	}
+
+	fields := htmlFieldSelectors(rules)
+	_, hasTitle := fields["title"]
+	_, hasURL := fields["url"]
+	items := make([]any, 0)
+	doc.Find(rules.List).Each(func(_ int, s *goquery.Selection) {
+		item := make(map[string]any, len(fields)+1)
+		for name, sel := range fields {
+			item[name] = selectValue(s, sel)
+		}
+
+		// 链接补全为绝对地址
+		if link, _ := item["url"].(string); link != "" {
+			item["url"] = resolveURL(pageURL, link)
+		}
+		if raw, _ := item["time"].(string); raw != "" {
+			if t, ok := parseHTMLTime(raw, rules.TimeLayout); ok {
+				item["published_at"] = t
+			}
+		}
+
+		// 没有标题也没有链接的通常是广告位或分隔行；只检查已配置的字段，两者都未配置时保留所有条目
+		title, _ := item["title"].(string)
+		link, _ := item["url"].(string)
+		if (hasTitle || hasURL) && title == "" && link == "" {
+			return
+		}
+		items = append(items, item)
+	})
+
+	page := map[string]any{
+		"title":  strings.TrimSpace(doc.Find("title").First().Text()),
+		"format": FormatHTML,
+	}
+	if pageURL != nil {
+		page["url"] = pageURL.String()
+	}
+	return map[string]any{
+		"page":  page,
+		"items": items,
+	}, nil
+}
+
+// selectValue 读取列表项内某个字段的文本或属性值
+func selectValue(item *goquery.Selection, sel model.FieldSelector) string {
+	target := item
+	if sel.Selector != "" {
+		target = item.Find(sel.Selector).First()
+	}
+	if target.Length() == 0 {
+		return ""
+	}
+	if sel.Attr != "" {
+		v, _ := target.Attr(sel.Attr)
+		return strings.TrimSpace(v)
+	}
+	return strings.Join(strings.Fields(target.Text()), " ")
+}
+
+// parseHTMLTime 优先使用配置的时间格式，否则复用 feed 的常见格式
+func parseHTMLTime(raw, layout string) (time.Time, bool) {
+	if layout != "" {
+		shanghai, _ := time.LoadLocation("Asia/Shanghai")
+		t, err := time.ParseInLocation(layout, raw, shanghai)
+		return t.UTC(), err == nil
+	}
+	return parseFeedTime(raw)
+}
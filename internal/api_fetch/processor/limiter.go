@@ -0,0 +1,156 @@
+package processor
+
+import (
+	"api-fetch/internal/api_fetch/metrics"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// defaultConcurrency 默认同时进行的抓取尝试数
+const defaultConcurrency = 8
+
+// RateLimit 令牌桶参数，RPS <= 0 表示不限速
+type RateLimit struct {
+	RPS   float64 `yaml:"rps"`   // 每秒请求数
+	Burst int     `yaml:"burst"` // 桶容量，默认 1
+}
+
+// FetchConfig 抓取并发与限速配置（config.yaml 中的 fetch 段）
+type FetchConfig struct {
+	Concurrency int                  `yaml:"concurrency"` // 全局同时进行的抓取尝试数（含重试），默认 8
+	RateLimit   RateLimit            `yaml:"rate_limit"`  // 每个 host 的默认限速
+	Hosts       map[string]RateLimit `yaml:"hosts"`       // 按 host 覆盖默认限速
+	Breaker     BreakerConfig        `yaml:"breaker"`     // 上游持续故障时熔断
+}
+
+// FetchLimiter 限制全局抓取并发，并对每个 host 的请求按令牌桶限速；nil 时不限制
+type FetchLimiter struct {
+	slots chan struct{}
+	cfg   FetchConfig
+
+	mu    sync.Mutex
+	hosts map[string]*rate.Limiter
+}
+
+// NewFetchLimiter 根据配置创建限制器
+func NewFetchLimiter(cfg FetchConfig) *FetchLimiter {
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = defaultConcurrency
+	}
+	hosts := make(map[string]RateLimit, len(cfg.Hosts))
+	for h, rl := range cfg.Hosts {
+		hosts[strings.ToLower(h)] = rl
+	}
+	cfg.Hosts = hosts
+	return &FetchLimiter{
+		slots: make(chan struct{}, cfg.Concurrency),
+		cfg:   cfg,
+		hosts: make(map[string]*rate.Limiter),
+	}
+}
+
+// fetchSlot 一次抓取尝试占用的全局槽位；等待 host 限速时暂时让出
+type fetchSlot struct {
+	l    *FetchLimiter
+	held bool
+}
+
+// acquire 占用一个抓取槽位，ctx 取消时返回错误；成功后须调用 release
+func (l *FetchLimiter) acquire(ctx context.Context) (*fetchSlot, error) {
+	slot := &fetchSlot{l: l}
+	if err := slot.take(ctx); err != nil {
+		return nil, err
+	}
+	return slot, nil
+}
+
+func (s *fetchSlot) take(ctx context.Context) error {
+	if s.l == nil {
+		return nil
+	}
+	select {
+	case s.l.slots <- struct{}{}:
+		s.held = true
+		metrics.FetchInFlight(1)
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release 归还槽位，未占用时不做任何事
+func (s *fetchSlot) release() {
+	if s == nil || !s.held {
+		return
+	}
+	s.held = false
+	<-s.l.slots
+	metrics.FetchInFlight(-1)
+}
+
+// waitHost 等待 host 的令牌桶放行一个请求；需要等待时先让出 slot（可为空），放行后重新占用，
+// 避免被限速的 host 占满全局槽位
+func (l *FetchLimiter) waitHost(ctx context.Context, host string, slot *fetchSlot) error {
+	if l == nil {
+		return nil
+	}
+	lim := l.hostLimiter(strings.ToLower(host))
+	if lim == nil {
+		return nil
+	}
+	r := lim.Reserve()
+	if !r.OK() {
+		return fmt.Errorf("rate limit for %s does not allow any request", host)
+	}
+	delay := r.Delay()
+	if delay == 0 {
+		return nil
+	}
+
+	held := slot != nil && slot.held
+	if held {
+		slot.release()
+	}
+	started := time.Now()
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		r.Cancel()
+		return ctx.Err()
+	}
+	metrics.RateLimitWait(host, time.Since(started))
+	if held {
+		return slot.take(ctx)
+	}
+	return nil
+}
+
+// hostLimiter 懒创建 host 的令牌桶，不限速的 host 返回 nil
+func (l *FetchLimiter) hostLimiter(host string) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if lim, ok := l.hosts[host]; ok {
+		return lim
+	}
+	rl, ok := l.cfg.Hosts[host]
+	if !ok {
+		rl = l.cfg.RateLimit
+	}
+	var lim *rate.Limiter
+	if rl.RPS > 0 {
+		burst := rl.Burst
+		if burst <= 0 {
+			burst = 1
+		}
+		lim = rate.NewLimiter(rate.Limit(rl.RPS), burst)
+	}
+	l.hosts[host] = lim
+	return lim
+}
@@ -0,0 +1,249 @@
+package processor
+
+import (
+	"api-fetch/internal/api_fetch/model"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// defaultMaxPages 未配置 MaxPages 时的最大抓取页数
+const defaultMaxPages = 10
+
+// pageRequest 单页请求参数
+type pageRequest struct {
+	index  int               // 页序号，从 0 开始
+	params map[string]string // 追加到 api.Params 的分页参数
+	url    string            // link 模式下替代 api.URL 的完整地址
+}
+
+// pageResponse 单页响应中与翻页相关的信息
+type pageResponse struct {
+	parsed map[string]any // 完整响应
+	header http.Header
+	url    *url.URL // 实际请求的地址，用于解析相对链接
+	items  int      // 本页条目数，未知时为 -1
+	empty  bool     // 本页没有数据，见 emptyPage
+}
+
+// pager 根据 Pagination 配置生成后续页的请求
+type pager struct {
+	cfg     *model.Pagination
+	cursors map[string]struct{} // 已使用的游标，防止死循环
+}
+
+func newPager(cfg *model.Pagination) *pager {
+	return &pager{cfg: cfg, cursors: make(map[string]struct{})}
+}
+
+// validatePagination 校验分页配置
+func validatePagination(cfg *model.Pagination) error {
+	if cfg == nil {
+		return nil
+	}
+	switch cfg.Type {
+	case "page":
+		if cfg.Param == "" {
+			return fmt.Errorf("pagination: page requires param")
+		}
+	case "offset":
+		if cfg.Param == "" || cfg.PageSize <= 0 {
+			return fmt.Errorf("pagination: offset requires param and page_size")
+		}
+	case "cursor":
+		if cfg.Param == "" || cfg.CursorField == "" {
+			return fmt.Errorf("pagination: cursor requires param and cursor_field")
+		}
+	case "link":
+	default:
+		return fmt.Errorf("pagination: unsupported type %q", cfg.Type)
+	}
+	switch cfg.Store {
+	case "", "merge", "linked":
+	default:
+		return fmt.Errorf("pagination: unsupported store %q", cfg.Store)
+	}
+	return nil
+}
+
+// first 第一页请求
+func (pg *pager) first() *pageRequest {
+	req := &pageRequest{index: 0}
+	if pg.cfg == nil {
+		return req
+	}
+	switch pg.cfg.Type {
+	case "page":
+		start := pg.cfg.Start
+		if start == 0 {
+			start = 1
+		}
+		req.params = pg.withSize(map[string]string{pg.cfg.Param: strconv.Itoa(start)})
+	case "offset":
+		req.params = pg.withSize(map[string]string{pg.cfg.Param: strconv.Itoa(pg.cfg.Start)})
+	}
+	return req
+}
+
+// next 根据上一页的响应计算下一页请求；返回 nil 表示停止翻页
+func (pg *pager) next(prev *pageRequest, resp *pageResponse) *pageRequest {
+	if pg.cfg == nil {
+		return nil
+	}
+	maxPages := pg.cfg.MaxPages
+	if maxPages <= 0 {
+		maxPages = defaultMaxPages
+	}
+	if prev.index+1 >= maxPages {
+		return nil
+	}
+	// 空页说明已经到底
+	if resp.items == 0 || resp.empty {
+		return nil
+	}
+	if pg.cfg.HasMoreField != "" {
+		if v, ok := lookupPath(resp.parsed, pg.cfg.HasMoreField); ok && !truthy(v) {
+			return nil
+		}
+	}
+
+	req := &pageRequest{index: prev.index + 1}
+	switch pg.cfg.Type {
+	case "page":
+		start := pg.cfg.Start
+		if start == 0 {
+			start = 1
+		}
+		req.params = pg.withSize(map[string]string{pg.cfg.Param: strconv.Itoa(start + req.index)})
+
+	case "offset":
+		// 不足一页说明已经到底
+		if resp.items >= 0 && resp.items < pg.cfg.PageSize {
+			return nil
+		}
+		offset := pg.cfg.Start + req.index*pg.cfg.PageSize
+		req.params = pg.withSize(map[string]string{pg.cfg.Param: strconv.Itoa(offset)})
+
+	case "cursor":
+		v, ok := lookupPath(resp.parsed, pg.cfg.CursorField)
+		if !ok || isEmpty(v) {
+			return nil
+		}
+		cursor := fmt.Sprint(v)
+		if _, used := pg.cursors[cursor]; used {
+			return nil
+		}
+		pg.cursors[cursor] = struct{}{}
+		req.params = map[string]string{pg.cfg.Param: cursor}
+
+	case "link":
+		next := nextLink(resp.header, resp.url)
+		if next == "" {
+			return nil
+		}
+		req.url = next
+
+	default:
+		return nil
+	}
+	return req
+}
+
+// emptyPage 条目数未知时判断本页是否为空：顶层的每个值都为空才算空页，如 {"list": [], "total": 0}；
+// 数值 0 与 false 视为空，常见于总数、是否有下一页等元数据
+func emptyPage(data map[string]any) bool {
+	for _, v := range data {
+		var empty bool
+		switch t := v.(type) {
+		case bool:
+			empty = !t
+		case int:
+			empty = t == 0
+		case int32:
+			empty = t == 0
+		case int64:
+			empty = t == 0
+		case float64:
+			empty = t == 0
+		default:
+			empty = isEmpty(v)
+		}
+		if !empty {
+			return false
+		}
+	}
+	return true
+}
+
+// withSize 附加每页条数参数
+func (pg *pager) withSize(params map[string]string) map[string]string {
+	if pg.cfg.SizeParam != "" && pg.cfg.PageSize > 0 {
+		params[pg.cfg.SizeParam] = strconv.Itoa(pg.cfg.PageSize)
+	}
+	return params
+}
+
+// nextLink 解析 RFC 8288 Link 头中 rel="next" 的地址
+func nextLink(header http.Header, base *url.URL) string {
+	for _, line := range header.Values("Link") {
+		for _, part := range strings.Split(line, ",") {
+			segs := strings.Split(part, ";")
+			target := strings.TrimSpace(segs[0])
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			for _, attr := range segs[1:] {
+				attr = strings.TrimSpace(attr)
+				if !strings.HasPrefix(strings.ToLower(attr), "rel=") {
+					continue
+				}
+				rels := strings.Fields(strings.Trim(attr[len("rel="):], `"`))
+				for _, rel := range rels {
+					if strings.EqualFold(rel, "next") {
+						return resolveURL(base, strings.Trim(target, "<>"))
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// resolveURL 将相对地址解析为基于 base 的绝对地址
+func resolveURL(base *url.URL, ref string) string {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ""
+	}
+	if base == nil {
+		return u.String()
+	}
+	return base.ResolveReference(u).String()
+}
+
+// truthy 判断响应字段是否为"真"
+func truthy(v any) bool {
+	switch t := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return t
+	case string:
+		if b, err := strconv.ParseBool(t); err == nil {
+			return b
+		}
+		return t != ""
+	case float64:
+		return t != 0
+	case int:
+		return t != 0
+	case int32:
+		return t != 0
+	case int64:
+		return t != 0
+	default:
+		return true
+	}
+}
@@ -0,0 +1,145 @@
+package processor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// pathSegment 路径中的一段：对象键、数组下标或 [*] 通配
+type pathSegment struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// parsePath 解析路径表达式，支持 "result.list[0].items"、"$.data"、"list[-1]"、"list[*].id"
+func parsePath(expr string) ([]pathSegment, error) {
+	expr = strings.TrimSpace(expr)
+	expr = strings.TrimPrefix(expr, "$")
+	expr = strings.TrimPrefix(expr, ".")
+	if expr == "" {
+		return nil, nil
+	}
+
+	var segs []pathSegment
+	for _, part := range strings.Split(expr, ".") {
+		if part == "" {
+			return nil, fmt.Errorf("invalid path %q: empty segment", expr)
+		}
+		// 拆出 key[0][1] 形式中的下标
+		key := part
+		rest := ""
+		if i := strings.IndexByte(part, '['); i >= 0 {
+			key, rest = part[:i], part[i:]
+		}
+		if key != "" {
+			segs = append(segs, pathSegment{key: key})
+		}
+		for rest != "" {
+			end := strings.IndexByte(rest, ']')
+			if rest[0] != '[' || end < 0 {
+				return nil, fmt.Errorf("invalid path %q: malformed index", expr)
+			}
+			inner := rest[1:end]
+			rest = rest[end+1:]
+			if inner == "*" {
+				segs = append(segs, pathSegment{wildcard: true})
+				continue
+			}
+			idx, err := strconv.Atoi(inner)
+			if err != nil {
+				return nil, fmt.Errorf("invalid path %q: index %q is not a number", expr, inner)
+			}
+			segs = append(segs, pathSegment{index: idx, isIndex: true})
+		}
+	}
+	return segs, nil
+}
+
+// lookupPath 按路径表达式读取嵌套字段；路径非法或不存在时返回 false
+// 路径中含 [*] 时返回所有匹配值组成的数组
+func lookupPath(obj any, path string) (any, bool) {
+	segs, err := parsePath(path)
+	if err != nil {
+		return nil, false
+	}
+	return walkPath(obj, segs)
+}
+
+func walkPath(cur any, segs []pathSegment) (any, bool) {
+	for i, seg := range segs {
+		switch {
+		case seg.wildcard:
+			arr, ok := asSlice(cur)
+			if !ok {
+				return nil, false
+			}
+			out := make([]any, 0, len(arr))
+			for _, item := range arr {
+				if v, ok := walkPath(item, segs[i+1:]); ok {
+					out = append(out, v)
+				}
+			}
+			return out, true
+
+		case seg.isIndex:
+			arr, ok := asSlice(cur)
+			if !ok {
+				return nil, false
+			}
+			idx := seg.index
+			if idx < 0 {
+				idx += len(arr)
+			}
+			if idx < 0 || idx >= len(arr) {
+				return nil, false
+			}
+			cur = arr[idx]
+
+		default:
+			m, ok := asMap(cur)
+			if !ok {
+				return nil, false
+			}
+			if cur, ok = m[seg.key]; !ok {
+				return nil, false
+			}
+		}
+	}
+	return cur, true
+}
+
+// asMap 兼容 JSON 解析结果和 MongoDB 解码出的各种文档类型
+func asMap(v any) (map[string]any, bool) {
+	switch t := v.(type) {
+	case map[string]any:
+		return t, true
+	case bson.M:
+		return t, true
+	case primitive.D:
+		m := make(map[string]any, len(t))
+		for _, e := range t {
+			m[e.Key] = e.Value
+		}
+		return m, true
+	default:
+		return nil, false
+	}
+}
+
+// asSlice 兼容 JSON 数组和 MongoDB 解码出的 primitive.A
+func asSlice(v any) ([]any, bool) {
+	switch t := v.(type) {
+	case []any:
+		return t, true
+	case primitive.A:
+		return t, true
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,255 @@
+package processor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// reasonMissing 字段不存在时的失败原因
+const reasonMissing = "missing field"
+
+// RequiredCheck 单个 Required 条件的校验结果
+//
+// Required 的键是路径表达式，值为标量时按字符串相等比较（兼容旧配置），
+// 值为对象且所有键都是 "$" 开头的已知操作符时按操作符校验，多个操作符同时满足才算通过，例如：
+//
+//	{"code": 0}
+//	{"status.ok": {"$eq": true}}
+//	{"code": {"$in": [0, 200]}}
+//	{"data.list": {"$exists": true}}
+//	{"msg": {"$regex": "^(ok|success)$"}}
+//	{"data.total": {"$gt": 0}}
+//
+// 其他对象按字面值整体比较，如 {"status": {"ok": true}}。
+type RequiredCheck struct {
+	Path   string `json:"path"`
+	Op     string `json:"op"`
+	Want   any    `json:"want"`
+	Got    any    `json:"got,omitempty"`
+	Passed bool   `json:"passed"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// requiredOps 支持的操作符
+var requiredOps = map[string]struct{}{
+	"$eq": {}, "$ne": {}, "$in": {}, "$nin": {}, "$exists": {}, "$regex": {},
+	"$gt": {}, "$gte": {}, "$lt": {}, "$lte": {},
+}
+
+// operatorMap want 为非空对象且所有键都是已知操作符时返回操作符表，否则按字面值比较
+func operatorMap(want any) (map[string]any, bool) {
+	m, ok := asMap(want)
+	if !ok || len(m) == 0 {
+		return nil, false
+	}
+	for name := range m {
+		if _, known := requiredOps[name]; !known {
+			return nil, false
+		}
+	}
+	return m, true
+}
+
+// evalRequired 按路径排序依次校验所有 Required 条件
+func evalRequired(obj map[string]any, required map[string]any) []RequiredCheck {
+	paths := make([]string, 0, len(required))
+	for path := range required {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var checks []RequiredCheck
+	for _, path := range paths {
+		got, exists := lookupPath(obj, path)
+		want := required[path]
+
+		ops, isOps := operatorMap(want)
+		if !isOps {
+			checks = append(checks, checkOp(path, "$eq", want, got, exists))
+			continue
+		}
+
+		names := make([]string, 0, len(ops))
+		for name := range ops {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			checks = append(checks, checkOp(path, name, ops[name], got, exists))
+		}
+	}
+	return checks
+}
+
+// checkOp 校验单个操作符
+func checkOp(path, op string, want, got any, exists bool) RequiredCheck {
+	c := RequiredCheck{Path: path, Op: op, Want: want, Got: got}
+
+	if op == "$exists" {
+		wantExists := truthy(want)
+		c.Passed = exists == wantExists
+		if !c.Passed {
+			c.Reason = fmt.Sprintf("exists=%v, want %v", exists, wantExists)
+		}
+		return c
+	}
+
+	if !exists {
+		c.Reason = reasonMissing
+		return c
+	}
+
+	switch op {
+	case "$eq":
+		c.Passed = valuesEqual(got, want)
+	case "$ne":
+		c.Passed = !valuesEqual(got, want)
+	case "$in", "$nin":
+		candidates, ok := asSlice(want)
+		if !ok {
+			c.Reason = op + " expects an array"
+			return c
+		}
+		found := false
+		for _, candidate := range candidates {
+			if valuesEqual(got, candidate) {
+				found = true
+				break
+			}
+		}
+		c.Passed = found == (op == "$in")
+	case "$regex":
+		pattern, ok := want.(string)
+		if !ok {
+			c.Reason = "$regex expects a string"
+			return c
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			c.Reason = fmt.Sprintf("invalid regex: %v", err)
+			return c
+		}
+		c.Passed = re.MatchString(fmt.Sprint(got))
+	case "$gt", "$gte", "$lt", "$lte":
+		gotNum, ok1 := toFloat(got)
+		wantNum, ok2 := toFloat(want)
+		if !ok1 || !ok2 {
+			c.Reason = "not a number"
+			return c
+		}
+		switch op {
+		case "$gt":
+			c.Passed = gotNum > wantNum
+		case "$gte":
+			c.Passed = gotNum >= wantNum
+		case "$lt":
+			c.Passed = gotNum < wantNum
+		case "$lte":
+			c.Passed = gotNum <= wantNum
+		}
+	default:
+		c.Reason = "unsupported operator"
+		return c
+	}
+
+	if !c.Passed && c.Reason == "" {
+		c.Reason = fmt.Sprintf("got %v", got)
+	}
+	return c
+}
+
+// validateRequiredOps 校验 Required 配置本身是否合法（操作符、正则、参数类型）
+func validateRequiredOps(required map[string]any) error {
+	for path, want := range required {
+		if _, err := parsePath(path); err != nil {
+			return fmt.Errorf("required: %w", err)
+		}
+		ops, isOps := operatorMap(want)
+		if !isOps {
+			// 含 "$" 开头的键但不全是已知操作符，多半是拼写错误
+			if m, ok := asMap(want); ok {
+				for name := range m {
+					if _, known := requiredOps[name]; !known && strings.HasPrefix(name, "$") {
+						return fmt.Errorf("required %s: unsupported operator %s", path, name)
+					}
+				}
+			}
+			continue
+		}
+		for op, arg := range ops {
+			switch op {
+			case "$eq", "$ne", "$exists":
+			case "$in", "$nin":
+				if _, ok := asSlice(arg); !ok {
+					return fmt.Errorf("required %s: %s expects an array", path, op)
+				}
+			case "$regex":
+				pattern, ok := arg.(string)
+				if !ok {
+					return fmt.Errorf("required %s: $regex expects a string", path)
+				}
+				if _, err := regexp.Compile(pattern); err != nil {
+					return fmt.Errorf("required %s: %w", path, err)
+				}
+			case "$gt", "$gte", "$lt", "$lte":
+				if _, ok := toFloat(arg); !ok {
+					return fmt.Errorf("required %s: %s expects a number", path, op)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// valuesEqual 数值按数值比较，对象与数组按 JSON 比较，其余按字符串比较
+func valuesEqual(a, b any) bool {
+	af, aok := toFloat(a)
+	bf, bok := toFloat(b)
+	if aok && bok {
+		return af == bf
+	}
+	if composite(a) || composite(b) {
+		ja, err1 := json.Marshal(a)
+		jb, err2 := json.Marshal(b)
+		return err1 == nil && err2 == nil && bytes.Equal(ja, jb)
+	}
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
+
+// composite 是否为对象或数组
+func composite(v any) bool {
+	if _, ok := asMap(v); ok {
+		return true
+	}
+	_, ok := asSlice(v)
+	return ok
+}
+
+// toFloat 将 JSON/BSON 中的数值（或数值字符串）转换为 float64
+func toFloat(v any) (float64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case float32:
+		return float64(t), true
+	case int:
+		return float64(t), true
+	case int32:
+		return float64(t), true
+	case int64:
+		return float64(t), true
+	case json.Number:
+		f, err := t.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(t), 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
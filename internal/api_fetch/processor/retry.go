@@ -0,0 +1,152 @@
+package processor
+
+import (
+	"api-fetch/internal/api_fetch/model"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// 默认重试策略
+const (
+	defaultMaxAttempts = 5
+	defaultBaseDelay   = 15 * time.Second
+	defaultMaxDelay    = 10 * time.Minute
+	defaultJitter      = 0.1
+
+	// maxRetryAfter 服务端要求等待超过此时长时不再重试，避免重试协程长时间挂起
+	maxRetryAfter = time.Hour
+)
+
+var (
+	// defaultRetryClasses 默认可重试的错误分类；配置、解析、校验类错误重试也不会成功
+	defaultRetryClasses = []string{ErrClassNetwork, ErrClassTimeout, ErrClassHTTPStatus, ErrClassStorage}
+	// defaultRetryStatuses 默认可重试的状态码
+	defaultRetryStatuses = []int{408, 425, 429, 500, 502, 503, 504}
+)
+
+// 可在 retry_on 中配置的错误分类；canceled 表示服务停止，始终不重试
+var retryableClasses = map[string]struct{}{
+	ErrClassConfig:     {},
+	ErrClassNetwork:    {},
+	ErrClassTimeout:    {},
+	ErrClassHTTPStatus: {},
+	ErrClassDecode:     {},
+	ErrClassParse:      {},
+	ErrClassRequired:   {},
+	ErrClassExtract:    {},
+	ErrClassStorage:    {},
+}
+
+// retryPolicy 补全默认值后的重试策略
+type retryPolicy struct {
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	jitter      float64
+	classes     map[string]struct{}
+	statuses    map[int]struct{}
+}
+
+// newRetryPolicy 解析 API 的重试配置，cfg 为空时返回默认策略
+func newRetryPolicy(cfg *model.RetryPolicy) (retryPolicy, error) {
+	rp := retryPolicy{
+		maxAttempts: defaultMaxAttempts,
+		baseDelay:   defaultBaseDelay,
+		maxDelay:    defaultMaxDelay,
+		jitter:      defaultJitter,
+	}
+	classes, statuses := defaultRetryClasses, defaultRetryStatuses
+	if cfg != nil {
+		if cfg.MaxAttempts < 0 {
+			return rp, fmt.Errorf("retry.max_attempts must not be negative")
+		}
+		if cfg.MaxAttempts > 0 {
+			rp.maxAttempts = cfg.MaxAttempts
+		}
+		if cfg.BaseDelay != "" {
+			d, err := time.ParseDuration(cfg.BaseDelay)
+			if err != nil || d <= 0 {
+				return rp, fmt.Errorf("retry.base_delay must be a positive duration, got %q", cfg.BaseDelay)
+			}
+			rp.baseDelay = d
+		}
+		if cfg.MaxDelay != "" {
+			d, err := time.ParseDuration(cfg.MaxDelay)
+			if err != nil || d <= 0 {
+				return rp, fmt.Errorf("retry.max_delay must be a positive duration, got %q", cfg.MaxDelay)
+			}
+			rp.maxDelay = d
+		}
+		if rp.maxDelay < rp.baseDelay {
+			return rp, fmt.Errorf("retry.max_delay must not be less than base_delay")
+		}
+		if cfg.Jitter != nil {
+			if *cfg.Jitter < 0 || *cfg.Jitter > 1 {
+				return rp, fmt.Errorf("retry.jitter must be between 0 and 1")
+			}
+			rp.jitter = *cfg.Jitter
+		}
+		if len(cfg.RetryOn) > 0 {
+			classes = cfg.RetryOn
+		}
+		if len(cfg.RetryStatuses) > 0 {
+			statuses = cfg.RetryStatuses
+		}
+	}
+
+	rp.classes = make(map[string]struct{}, len(classes))
+	for _, c := range classes {
+		if _, ok := retryableClasses[c]; !ok {
+			return rp, fmt.Errorf("retry.retry_on: unknown error class %q", c)
+		}
+		rp.classes[c] = struct{}{}
+	}
+	rp.statuses = make(map[int]struct{}, len(statuses))
+	for _, code := range statuses {
+		if code < 100 || code > 599 {
+			return rp, fmt.Errorf("retry.retry_statuses: invalid status code %d", code)
+		}
+		rp.statuses[code] = struct{}{}
+	}
+	return rp, nil
+}
+
+// retryable 错误是否值得重试：分类在 retry_on 中，http_status 还要求状态码在 retry_statuses 中
+func (rp retryPolicy) retryable(err error) bool {
+	class := ErrorClass(err)
+	if _, ok := rp.classes[class]; !ok {
+		return false
+	}
+	status, _ := responseInfo(err)
+	if class == ErrClassHTTPStatus && status != 0 {
+		_, ok := rp.statuses[status]
+		return ok
+	}
+	return true
+}
+
+// delay 第 retry 次重试（从 1 开始）前的等待时间：baseDelay * 2^(retry-1) 按 jitter 随机浮动，
+// 不超过 maxDelay；响应带 Retry-After 时至少等待其要求的时长
+func (rp retryPolicy) delay(retry int, err error) time.Duration {
+	d := rp.baseDelay
+	for i := 1; i < retry && d < rp.maxDelay; i++ {
+		d *= 2
+	}
+	if rp.jitter > 0 {
+		d += time.Duration((rand.Float64()*2 - 1) * rp.jitter * float64(d))
+	}
+	if d > rp.maxDelay {
+		d = rp.maxDelay
+	}
+	if _, after := responseInfo(err); after > d {
+		d = after
+	}
+	return d
+}
+
+// validateRetryPolicy 校验 API 的重试配置
+func validateRetryPolicy(cfg *model.RetryPolicy) error {
+	_, err := newRetryPolicy(cfg)
+	return err
+}
@@ -0,0 +1,291 @@
+package processor
+
+import (
+	"api-fetch/internal/api_fetch/alert"
+	"api-fetch/internal/api_fetch/model"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+// 指纹的规模限制，防止超大或深层嵌套的响应撑爆文档
+const (
+	schemaMaxDepth  = 8
+	schemaMaxFields = 1000
+)
+
+// 值类型
+const (
+	typeObject   = "object"
+	typeArray    = "array"
+	typeString   = "string"
+	typeNumber   = "number"
+	typeBool     = "bool"
+	typeNull     = "null"
+	typeDate     = "date"
+	typeObjectID = "objectid"
+)
+
+// fingerprint 单次响应的结构：字段路径 -> 值类型集合
+type fingerprint struct {
+	fields map[string]map[string]struct{}
+	// emptyArrays 本次为空的数组路径，其下的字段无法判断是否缺失
+	emptyArrays []string
+}
+
+// buildFingerprint 遍历提取后的数据，数组元素统一记为 [*]
+func buildFingerprint(data bson.M) *fingerprint {
+	fp := &fingerprint{fields: make(map[string]map[string]struct{})}
+	fp.walk("", data, 0)
+	return fp
+}
+
+func (fp *fingerprint) add(path, typ string) {
+	if path == "" {
+		return
+	}
+	types, ok := fp.fields[path]
+	if !ok {
+		if len(fp.fields) >= schemaMaxFields {
+			return
+		}
+		types = make(map[string]struct{})
+		fp.fields[path] = types
+	}
+	types[typ] = struct{}{}
+}
+
+func (fp *fingerprint) walk(path string, v any, depth int) {
+	if m, ok := asMap(v); ok {
+		fp.add(path, typeObject)
+		if depth >= schemaMaxDepth {
+			return
+		}
+		for k, child := range m {
+			fp.walk(joinPath(path, k), child, depth+1)
+		}
+		return
+	}
+	if arr, ok := asSlice(v); ok {
+		fp.add(path, typeArray)
+		if len(arr) == 0 {
+			fp.emptyArrays = append(fp.emptyArrays, path+"[*]")
+			return
+		}
+		if depth >= schemaMaxDepth {
+			return
+		}
+		for _, child := range arr {
+			fp.walk(path+"[*]", child, depth+1)
+		}
+		return
+	}
+	fp.add(path, valueType(v))
+}
+
+func joinPath(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+// valueType 值类型名，整数与浮点数统一为 number
+func valueType(v any) string {
+	switch v.(type) {
+	case nil:
+		return typeNull
+	case string:
+		return typeString
+	case bool:
+		return typeBool
+	case int, int32, int64, float32, float64, primitive.Decimal128:
+		return typeNumber
+	case time.Time, primitive.DateTime:
+		return typeDate
+	case primitive.ObjectID:
+		return typeObjectID
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// underEmptyArray 字段是否位于本次为空的数组下
+func (fp *fingerprint) underEmptyArray(path string) bool {
+	for _, prefix := range fp.emptyArrays {
+		if path == prefix || strings.HasPrefix(path, prefix+".") || strings.HasPrefix(path, prefix+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+// diffSchema 将本次指纹与基线比较并合并到基线：
+//   - added：基线中从未出现过的字段；
+//   - removed：基线中每次都出现、本次缺失的字段（位于空数组下的除外）；
+//   - retyped：本次出现了基线中没有的值类型（null 视为可空，不算变化）。
+//
+// 合并后新字段与缺失字段都不再是"每次都有"，同一变化只会报告一次
+func diffSchema(base *model.SchemaFingerprint, fp *fingerprint) (added, removed []string, retyped []model.RetypedField) {
+	known := make(map[string]*model.SchemaField, len(base.Fields))
+	for i := range base.Fields {
+		known[base.Fields[i].Path] = &base.Fields[i]
+	}
+
+	for _, f := range base.Fields {
+		if _, ok := fp.fields[f.Path]; ok {
+			continue
+		}
+		if f.Hits == base.Samples && !fp.underEmptyArray(f.Path) {
+			removed = append(removed, f.Path)
+		}
+	}
+
+	// 新字段在循环结束后再追加，避免 append 扩容后 known 中的指针指向旧数组
+	var newFields []model.SchemaField
+	for path, types := range fp.fields {
+		f, ok := known[path]
+		if !ok {
+			added = append(added, path)
+			newFields = append(newFields, model.SchemaField{Path: path, Types: sortedTypes(types), Hits: 1})
+			continue
+		}
+		f.Hits++
+		for typ := range types {
+			if containsString(f.Types, typ) {
+				continue
+			}
+			if typ != typeNull && base.Samples > 0 {
+				retyped = append(retyped, model.RetypedField{Path: path, From: append([]string(nil), f.Types...), To: typ})
+			}
+			f.Types = append(f.Types, typ)
+			sort.Strings(f.Types)
+		}
+	}
+	base.Fields = append(base.Fields, newFields...)
+	base.Samples++
+
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Slice(retyped, func(i, j int) bool { return retyped[i].Path < retyped[j].Path })
+	sort.Slice(base.Fields, func(i, j int) bool { return base.Fields[i].Path < base.Fields[j].Path })
+	return added, removed, retyped
+}
+
+func sortedTypes(types map[string]struct{}) []string {
+	out := make([]string, 0, len(types))
+	for t := range types {
+		out = append(out, t)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSchema 比较本次提取数据与已学习的结构，有变化时记录到 schema_drifts 并告警；
+// 首次抓取时只学习不比较。失败只记日志，不影响抓取结果
+func (p *Processor) checkSchema(ctx context.Context, api *model.APIInfo, data bson.M, runID string) {
+	if p.Stores == nil || p.Stores.Schemas == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), recordAttemptTimeout)
+	defer cancel()
+
+	key := api.ID
+	if key == "" {
+		key = processorKey(api.Source, api.Category, api.InfoType)
+	}
+	log := p.Log.With(zap.String("source", api.Source), zap.String("category", api.Category))
+
+	now := time.Now().UTC()
+	var base model.SchemaFingerprint
+	err := p.Stores.Schemas.FindOne(ctx, bson.M{"_id": key}).Decode(&base)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		base = model.SchemaFingerprint{ID: key, LearnedAt: now}
+	} else if err != nil {
+		log.Warn("Failed to load schema fingerprint", zap.Error(err))
+		return
+	}
+	base.Source, base.Category, base.InfoType = api.Source, api.Category, api.InfoType
+	firstSample := base.Samples == 0
+
+	added, removed, retyped := diffSchema(&base, buildFingerprint(data))
+	base.UpdatedAt = now
+	if _, err := p.Stores.Schemas.ReplaceOne(ctx, bson.M{"_id": key}, base, options.Replace().SetUpsert(true)); err != nil {
+		log.Warn("Failed to save schema fingerprint", zap.Error(err))
+	}
+	if firstSample || (len(added) == 0 && len(removed) == 0 && len(retyped) == 0) {
+		return
+	}
+
+	drift := model.SchemaDrift{
+		APIID:      key,
+		RunID:      runID,
+		Source:     api.Source,
+		Category:   api.Category,
+		InfoType:   api.InfoType,
+		Added:      added,
+		Removed:    removed,
+		Retyped:    retyped,
+		DetectedAt: now,
+	}
+	if _, err := p.Stores.SchemaDrifts.InsertOne(ctx, drift); err != nil {
+		log.Warn("Failed to record schema drift", zap.Error(err))
+	}
+	log.Warn("Upstream schema drift detected",
+		zap.Strings("added", added),
+		zap.Strings("removed", removed),
+		zap.Int("retyped", len(retyped)),
+	)
+
+	fields := map[string]string{}
+	if len(added) > 0 {
+		fields["added"] = strings.Join(truncateList(added, 10), ", ")
+	}
+	if len(removed) > 0 {
+		fields["removed"] = strings.Join(truncateList(removed, 10), ", ")
+	}
+	if len(retyped) > 0 {
+		var parts []string
+		for _, r := range retyped {
+			parts = append(parts, fmt.Sprintf("%s: %s -> %s", r.Path, strings.Join(r.From, "|"), r.To))
+		}
+		fields["retyped"] = strings.Join(truncateList(parts, 10), ", ")
+	}
+	p.Alerts.Notify(alert.Alert{
+		Event:    alert.EventSchemaDrift,
+		Source:   api.Source,
+		Category: api.Category,
+		InfoType: api.InfoType,
+		APIID:    api.ID,
+		Title:    fmt.Sprintf("%s/%s response schema changed", api.Source, api.Category),
+		Message:  fmt.Sprintf("%d added, %d removed, %d retyped fields", len(added), len(removed), len(retyped)),
+		Fields:   fields,
+	})
+}
+
+// truncateList 最多保留 n 项，超出部分以省略说明代替
+func truncateList(list []string, n int) []string {
+	if len(list) <= n {
+		return list
+	}
+	out := append([]string(nil), list[:n]...)
+	return append(out, fmt.Sprintf("... %d more", len(list)-n))
+}
@@ -0,0 +1,83 @@
+package processor
+
+import (
+	"api-fetch/internal/api_fetch/model"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestDiffSchemaMergesIntoBaseline(t *testing.T) {
+	// 与 BSON 解码结果一致：cap == len，首次追加新字段就会扩容
+	fields := make([]model.SchemaField, 2, 2)
+	fields[0] = model.SchemaField{Path: "a", Types: []string{typeNumber}, Hits: 1}
+	fields[1] = model.SchemaField{Path: "b", Types: []string{typeString}, Hits: 1}
+	base := &model.SchemaFingerprint{Samples: 1, Fields: fields}
+
+	added, removed, retyped := diffSchema(base, buildFingerprint(bson.M{"a": "x", "b": "y", "c": 1, "d": 2}))
+	if want := []string{"c", "d"}; !reflect.DeepEqual(added, want) {
+		t.Fatalf("added = %v, want %v", added, want)
+	}
+	if len(removed) != 0 {
+		t.Fatalf("removed = %v, want none", removed)
+	}
+	wantRetyped := []model.RetypedField{{Path: "a", From: []string{typeNumber}, To: typeString}}
+	if !reflect.DeepEqual(retyped, wantRetyped) {
+		t.Fatalf("retyped = %v, want %v", retyped, wantRetyped)
+	}
+
+	wantFields := []model.SchemaField{
+		{Path: "a", Types: []string{typeNumber, typeString}, Hits: 2},
+		{Path: "b", Types: []string{typeString}, Hits: 2},
+		{Path: "c", Types: []string{typeNumber}, Hits: 1},
+		{Path: "d", Types: []string{typeNumber}, Hits: 1},
+	}
+	if base.Samples != 2 {
+		t.Fatalf("samples = %d, want 2", base.Samples)
+	}
+	if !reflect.DeepEqual(base.Fields, wantFields) {
+		t.Fatalf("fields = %+v, want %+v", base.Fields, wantFields)
+	}
+
+	// 同一变化只报告一次；新字段缺失不算 removed
+	added, removed, retyped = diffSchema(base, buildFingerprint(bson.M{"a": 1, "b": "y"}))
+	if len(added) != 0 || len(removed) != 0 || len(retyped) != 0 {
+		t.Fatalf("second diff = %v %v %v, want no changes", added, removed, retyped)
+	}
+
+	// 每次都出现的字段缺失时报告 removed
+	_, removed, _ = diffSchema(base, buildFingerprint(bson.M{"c": 1}))
+	if want := []string{"a", "b"}; !reflect.DeepEqual(removed, want) {
+		t.Fatalf("removed = %v, want %v", removed, want)
+	}
+}
+
+func TestDiffSchemaIgnoresFieldsUnderEmptyArray(t *testing.T) {
+	base := &model.SchemaFingerprint{
+		Samples: 1,
+		Fields: []model.SchemaField{
+			{Path: "items", Types: []string{typeArray}, Hits: 1},
+			{Path: "items[*]", Types: []string{typeObject}, Hits: 1},
+			{Path: "items[*].id", Types: []string{typeNumber}, Hits: 1},
+		},
+	}
+	added, removed, retyped := diffSchema(base, buildFingerprint(bson.M{"items": bson.A{}}))
+	if len(added) != 0 || len(removed) != 0 || len(retyped) != 0 {
+		t.Fatalf("diff = %v %v %v, want no changes", added, removed, retyped)
+	}
+}
+
+func TestDiffSchemaNullIsNotRetype(t *testing.T) {
+	base := &model.SchemaFingerprint{
+		Samples: 1,
+		Fields:  []model.SchemaField{{Path: "a", Types: []string{typeString}, Hits: 1}},
+	}
+	_, _, retyped := diffSchema(base, buildFingerprint(bson.M{"a": nil}))
+	if len(retyped) != 0 {
+		t.Fatalf("retyped = %v, want none", retyped)
+	}
+	if want := []string{typeNull, typeString}; !reflect.DeepEqual(base.Fields[0].Types, want) {
+		t.Fatalf("types = %v, want %v", base.Fields[0].Types, want)
+	}
+}
@@ -0,0 +1,231 @@
+package processor
+
+import (
+	"api-fetch/internal/api_fetch/model"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"runtime/metrics"
+	"time"
+
+	"github.com/dop251/goja"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// 脚本执行的默认限制
+const (
+	defaultScriptTimeout   = 2 * time.Second
+	defaultScriptMaxMemory = 64 << 20
+	scriptMaxCallStack     = 1024
+	scriptMemoryCheckEvery = 20 * time.Millisecond
+)
+
+// heapObjectsMetric 当前堆上存活对象的字节数，读取时不会 stop-the-world
+const heapObjectsMetric = "/memory/classes/heap/objects:bytes"
+
+// compiledScript 预编译的转换脚本，按代码哈希缓存
+type compiledScript struct {
+	hash      string
+	program   *goja.Program
+	timeout   time.Duration
+	maxMemory uint64
+}
+
+// scriptHash 脚本代码的哈希，用于判断缓存是否失效
+func scriptHash(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// compileScript 编译脚本并校验是否定义了 transform 函数；顶层代码与正式执行一样受超时和内存限制约束
+func compileScript(key, code string, timeout time.Duration, maxMemory uint64) (*goja.Program, error) {
+	if code == "" {
+		return nil, fmt.Errorf("script: code is required")
+	}
+	program, err := goja.Compile(key+".js", code, true)
+	if err != nil {
+		return nil, fmt.Errorf("script: %w", err)
+	}
+
+	// 试运行一次顶层代码，确认 transform 已定义
+	vm := goja.New()
+	vm.SetMaxCallStackSize(scriptMaxCallStack)
+	stop := watchScript(context.Background(), vm, timeout, maxMemory)
+	defer stop()
+	if _, err := vm.RunProgram(program); err != nil {
+		return nil, fmt.Errorf("script: %w", err)
+	}
+	if _, ok := goja.AssertFunction(vm.Get("transform")); !ok {
+		return nil, fmt.Errorf("script: function transform(data, doc) is not defined")
+	}
+	return program, nil
+}
+
+// watchScript 监控脚本执行：超时、ctx 取消或堆增长超限时中断 vm，返回的函数用于停止监控。
+// 内存按进程级堆统计，并发执行多个脚本时只能近似归因到当前脚本
+func watchScript(ctx context.Context, vm *goja.Runtime, timeout time.Duration, maxMemory uint64) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		ticker := time.NewTicker(scriptMemoryCheckEvery)
+		defer ticker.Stop()
+		baseline := heapObjectsBytes()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				vm.Interrupt(ctx.Err())
+				return
+			case <-timer.C:
+				vm.Interrupt(fmt.Errorf("script timeout after %s", timeout))
+				return
+			case <-ticker.C:
+				if used := heapObjectsBytes(); used > baseline && used-baseline > maxMemory {
+					vm.Interrupt(fmt.Errorf("script memory limit exceeded: %d MB", maxMemory>>20))
+					return
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// loadScript 返回 key 对应的脚本，代码未变化时复用已编译的程序
+func (dp *DataProcessor) loadScript(key string, st *model.ScriptTransform) (*compiledScript, error) {
+	cs := &compiledScript{
+		hash:      scriptHash(st.Code),
+		timeout:   defaultScriptTimeout,
+		maxMemory: defaultScriptMaxMemory,
+	}
+	if st.TimeoutMS > 0 {
+		cs.timeout = time.Duration(st.TimeoutMS) * time.Millisecond
+	}
+	if st.MaxMemoryMB > 0 {
+		cs.maxMemory = uint64(st.MaxMemoryMB) << 20
+	}
+
+	dp.mu.RLock()
+	cached, ok := dp.scripts[key]
+	dp.mu.RUnlock()
+	if ok && cached.hash == cs.hash {
+		cs.program = cached.program
+		return cs, nil
+	}
+
+	program, err := compileScript(key, st.Code, cs.timeout, cs.maxMemory)
+	if err != nil {
+		return nil, err
+	}
+	cs.program = program
+	dp.Log.Info("Transform script compiled", zap.String("processorKey", key), zap.String("hash", cs.hash[:12]))
+	return cs, nil
+}
+
+// scriptProcessor 将脚本包装为 DataProcessorFunc
+func (dp *DataProcessor) scriptProcessor(key string, cs *compiledScript) DataProcessorFunc {
+	return func(ctx context.Context, doc *model.CrawlResult) (*model.ProcessedData, error) {
+		if doc.Data == nil {
+			return nil, fmt.Errorf("empty data")
+		}
+
+		data, err := cs.run(ctx, doc, dp.Log.With(zap.String("processorKey", key)))
+		if err != nil {
+			return nil, err
+		}
+
+		return &model.ProcessedData{
+			Source:      doc.Source,
+			Category:    doc.Category,
+			InfoType:    doc.InfoType,
+			Date:        doc.Date,
+			ProcessedAt: time.Now(),
+			Data:        data,
+			RawDocID:    doc.ID.Hex(),
+		}, nil
+	}
+}
+
+// run 在独立的 goja 运行时中执行脚本，超时、ctx 取消或堆增长超限（近似值，见 watchScript）时中断
+func (cs *compiledScript) run(ctx context.Context, doc *model.CrawlResult, log *zap.Logger) (map[string]interface{}, error) {
+	vm := goja.New()
+	vm.SetMaxCallStackSize(scriptMaxCallStack)
+
+	console := vm.NewObject()
+	_ = console.Set("log", func(call goja.FunctionCall) goja.Value {
+		args := make([]interface{}, len(call.Arguments))
+		for i, a := range call.Arguments {
+			args[i] = a.Export()
+		}
+		log.Debug("script log", zap.Any("args", args))
+		return goja.Undefined()
+	})
+	_ = vm.Set("console", console)
+
+	stop := watchScript(ctx, vm, cs.timeout, cs.maxMemory)
+	defer stop()
+
+	if _, err := vm.RunProgram(cs.program); err != nil {
+		return nil, fmt.Errorf("script init: %w", err)
+	}
+	transform, ok := goja.AssertFunction(vm.Get("transform"))
+	if !ok {
+		return nil, fmt.Errorf("script: function transform(data, doc) is not defined")
+	}
+
+	meta := map[string]interface{}{
+		"id":        doc.ID.Hex(),
+		"source":    doc.Source,
+		"category":  doc.Category,
+		"info_type": doc.InfoType,
+		"date":      doc.Date,
+	}
+	result, err := transform(goja.Undefined(), vm.ToValue(plainValue(doc.Data)), vm.ToValue(meta))
+	if err != nil {
+		return nil, fmt.Errorf("script: %w", err)
+	}
+
+	out, ok := result.Export().(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("script: transform must return an object, got %T", result.Export())
+	}
+	return out, nil
+}
+
+// heapObjectsBytes 读取当前堆对象占用
+func heapObjectsBytes() uint64 {
+	sample := []metrics.Sample{{Name: heapObjectsMetric}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return sample[0].Value.Uint64()
+}
+
+// plainValue 将 BSON 解码出的文档/数组转换为普通 map/slice，脚本中才能按原生对象和数组使用
+func plainValue(v interface{}) interface{} {
+	if m, ok := asMap(v); ok {
+		out := make(map[string]interface{}, len(m))
+		for k, val := range m {
+			out[k] = plainValue(val)
+		}
+		return out
+	}
+	if arr, ok := asSlice(v); ok {
+		out := make([]interface{}, len(arr))
+		for i, val := range arr {
+			out[i] = plainValue(val)
+		}
+		return out
+	}
+	if oid, ok := v.(primitive.ObjectID); ok {
+		return oid.Hex()
+	}
+	if dt, ok := v.(primitive.DateTime); ok {
+		return dt.Time()
+	}
+	return v
+}
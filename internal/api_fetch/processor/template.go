@@ -0,0 +1,357 @@
+package processor
+
+import (
+	"api-fetch/internal/api_fetch/model"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// secretEnvPrefix secret 引用对应的环境变量前缀：{{secret "PAPER_TOKEN"}} 读取 API_FETCH_SECRET_PAPER_TOKEN
+const secretEnvPrefix = "API_FETCH_SECRET_"
+
+// maxNonceBytes nonce 的最大字节数
+const maxNonceBytes = 64
+
+// secretMask 日志、抓取记录与试运行结果中替代密钥的字符串
+const secretMask = "***"
+
+// templateData 请求模板可用的变量
+type templateData struct {
+	Now     time.Time // 本次运行的时间，时区为 API 计划的时区（默认 Asia/Shanghai）
+	Date    string    // Now 的日期，YYYY-MM-DD
+	Attempt int       // 第几次尝试
+	Page    int       // 第几页，从 1 开始
+
+	secrets *secretSet // 本次渲染中 secret 调用的解析与记录，模板中不可见
+}
+
+// newTemplateData 以运行时间 now 生成模板变量
+func newTemplateData(api *model.APIInfo, now time.Time, attempt int, pageReq *pageRequest) templateData {
+	loc := shanghaiLocation()
+	if api.Schedule != nil && api.Schedule.Timezone != "" {
+		if l, err := time.LoadLocation(api.Schedule.Timezone); err == nil {
+			loc = l
+		}
+	}
+	local := now.In(loc)
+	return templateData{
+		Now:     local,
+		Date:    local.Format("2006-01-02"),
+		Attempt: attempt,
+		Page:    pageReq.index + 1,
+		secrets: &secretSet{},
+	}
+}
+
+// secretSet 解析 secret 引用并记录取到的值，用于脱敏；只在一次请求的构建中使用，不需要加锁
+type secretSet struct {
+	stub   bool // 不读取环境变量，secret 渲染为空串
+	refs   int  // secret 调用次数
+	values []string
+}
+
+// lookup 模板中 secret 函数的实现
+func (s *secretSet) lookup(name string) (string, error) {
+	s.refs++
+	if s.stub {
+		return "", nil
+	}
+	v, err := lookupSecret(name)
+	if err != nil || v == "" {
+		return v, err
+	}
+	s.values = append(s.values, v)
+	return v, nil
+}
+
+// mask 将 text 中出现的密钥（含 URL 与 JSON 转义后的形式）替换为 ***
+func (s *secretSet) mask(text string) string {
+	if s == nil {
+		return text
+	}
+	for _, v := range s.values {
+		forms := []string{v, url.QueryEscape(v), url.PathEscape(v)}
+		// json 函数与 json.Marshal 会转义 <、>、&，其他 JSON 编码器通常不转义，两种形式都替换
+		for _, escapeHTML := range []bool{true, false} {
+			var buf bytes.Buffer
+			enc := json.NewEncoder(&buf)
+			enc.SetEscapeHTML(escapeHTML)
+			if err := enc.Encode(v); err == nil {
+				forms = append(forms, strings.Trim(strings.TrimSpace(buf.String()), `"`))
+			}
+		}
+		for _, form := range forms {
+			text = strings.ReplaceAll(text, form, secretMask)
+		}
+	}
+	return text
+}
+
+// templateFuncs 请求模板可用的函数
+var templateFuncs = template.FuncMap{
+	// offset "-1d" .Now：按偏移量调整时间，支持 w / d / h / m / s 及其组合，如 "-1d12h"
+	"offset": func(offset string, t time.Time) (time.Time, error) {
+		d, err := parseOffset(offset)
+		if err != nil {
+			return t, err
+		}
+		return t.Add(d), nil
+	},
+	// in "America/New_York" .Now：转换到指定时区
+	"in": func(name string, t time.Time) (time.Time, error) {
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			return t, err
+		}
+		return t.In(loc), nil
+	},
+	// format "20060102" .Now：按 Go layout 格式化
+	"format": func(layout string, t time.Time) string { return t.Format(layout) },
+	// unixMilli .Now：毫秒时间戳
+	"unixMilli": func(t time.Time) int64 { return t.UnixMilli() },
+	// nonce / nonce 32：随机十六进制串，默认 16 字节，最多 maxNonceBytes 字节
+	"nonce": func(n ...int) (string, error) {
+		size := 16
+		if len(n) > 0 && n[0] > 0 {
+			size = n[0]
+		}
+		if size > maxNonceBytes {
+			return "", fmt.Errorf("nonce size %d exceeds %d bytes", size, maxNonceBytes)
+		}
+		return randomHex(size)
+	},
+	// json .Date：编码为 JSON 字面量（字符串带引号并转义），用于 body 中插入变量与密钥
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	},
+	// secret "NAME"：读取环境变量 API_FETCH_SECRET_NAME，不存在时报错；渲染时替换为 templateData.secrets 的解析
+	"secret": lookupSecret,
+}
+
+// randomHex n 字节的随机十六进制串
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// lookupSecret 读取 secret 引用，密钥不写入 apis 集合
+func lookupSecret(name string) (string, error) {
+	v, ok := os.LookupEnv(secretEnvPrefix + name)
+	if !ok {
+		return "", fmt.Errorf("secret %q is not set (env %s%s)", name, secretEnvPrefix, name)
+	}
+	return v, nil
+}
+
+// parseOffset 解析 "-1d"、"+2h30m"、"1w" 等偏移量；d、w 按 24 小时计
+func parseOffset(s string) (time.Duration, error) {
+	raw := strings.TrimSpace(s)
+	if raw == "" {
+		return 0, fmt.Errorf("empty offset")
+	}
+	sign := time.Duration(1)
+	rest := raw
+	switch rest[0] {
+	case '-':
+		sign, rest = -1, rest[1:]
+	case '+':
+		rest = rest[1:]
+	}
+	if rest == "" {
+		return 0, fmt.Errorf("invalid offset %q", raw)
+	}
+
+	var total time.Duration
+	for rest != "" {
+		i := 0
+		for i < len(rest) && rest[i] >= '0' && rest[i] <= '9' {
+			i++
+		}
+		if i == 0 || i == len(rest) {
+			return 0, fmt.Errorf("invalid offset %q", raw)
+		}
+		n, err := strconv.Atoi(rest[:i])
+		if err != nil {
+			return 0, fmt.Errorf("invalid offset %q", raw)
+		}
+		var unit time.Duration
+		switch rest[i] {
+		case 'w':
+			unit = 7 * 24 * time.Hour
+		case 'd':
+			unit = 24 * time.Hour
+		case 'h':
+			unit = time.Hour
+		case 'm':
+			unit = time.Minute
+		case 's':
+			unit = time.Second
+		default:
+			return 0, fmt.Errorf("invalid offset unit %q in %q", rest[i], raw)
+		}
+		total += time.Duration(n) * unit
+		rest = rest[i+1:]
+	}
+	return sign * total, nil
+}
+
+// isTemplate 只有包含 {{ 的字符串才按模板渲染
+func isTemplate(s string) bool {
+	return strings.Contains(s, "{{")
+}
+
+func parseTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+}
+
+// renderTemplate 渲染单个字符串，非模板原样返回
+func renderTemplate(name, text string, data templateData) (string, error) {
+	if !isTemplate(text) {
+		return text, nil
+	}
+	tmpl, err := parseTemplate(name, text)
+	if err != nil {
+		return "", err
+	}
+	if data.secrets != nil {
+		tmpl.Funcs(template.FuncMap{"secret": data.secrets.lookup})
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// renderMap 渲染 map 中的每个值，name 用于错误信息
+func renderMap(name string, m map[string]string, data templateData) (map[string]string, error) {
+	if len(m) == 0 {
+		return m, nil
+	}
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		r, err := renderTemplate(name+"."+k, v, data)
+		if err != nil {
+			return nil, err
+		}
+		out[k] = r
+	}
+	return out, nil
+}
+
+// renderedRequest 渲染后的请求配置
+type renderedRequest struct {
+	url     string
+	params  map[string]string
+	headers map[string]string
+	body    string
+}
+
+// renderRequest 渲染 API 的 url、params、headers 与 body
+func renderRequest(api *model.APIInfo, data templateData) (*renderedRequest, error) {
+	var (
+		r   renderedRequest
+		err error
+	)
+	if r.url, err = renderTemplate("url", api.URL, data); err != nil {
+		return nil, err
+	}
+	if r.params, err = renderMap("params", api.Params, data); err != nil {
+		return nil, err
+	}
+	if r.headers, err = renderMap("headers", api.Headers, data); err != nil {
+		return nil, err
+	}
+	if r.body, err = renderTemplate("body", api.Body, data); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// previewTemplate 按 data 试渲染，secret 只检查引用形式、渲染为空串，不要求保存配置时密钥已存在
+func previewTemplate(name, text string, data templateData) (string, error) {
+	data.secrets = &secretSet{stub: true}
+	return renderTemplate(name, text, data)
+}
+
+// secretRefs 试渲染 text（secret 渲染为空串），返回 secret 调用次数与其余部分的渲染结果
+func secretRefs(name, text string) (int, string, error) {
+	data := newTemplateData(&model.APIInfo{}, time.Now(), 1, &pageRequest{})
+	data.secrets.stub = true
+	rest, err := renderTemplate(name, text, data)
+	return data.secrets.refs, rest, err
+}
+
+// requireSecretRef 凭据字段只能由 secret 引用组成，明文凭据不写入 apis 与 api_audit，也不会被查询接口返回
+func requireSecretRef(name, text string) error {
+	refs, rest, err := secretRefs(name, text)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if refs == 0 || strings.TrimSpace(rest) != "" {
+		return fmt.Errorf(`%s must be a {{secret "NAME"}} reference, literal credentials are not accepted`, name)
+	}
+	return nil
+}
+
+// validateTemplates 按当前时间试渲染 url、params、headers、body 中的模板，检查语法与变量名
+func validateTemplates(api *model.APIInfo) error {
+	data := newTemplateData(api, time.Now(), 1, &pageRequest{})
+	check := func(name, text string) error {
+		if _, err := previewTemplate(name, text, data); err != nil {
+			return fmt.Errorf("invalid template in %s: %w", name, err)
+		}
+		return nil
+	}
+	if err := check("url", api.URL); err != nil {
+		return err
+	}
+	for k, v := range api.Params {
+		if err := check("params."+k, v); err != nil {
+			return err
+		}
+	}
+	for k, v := range api.Headers {
+		if err := check("headers."+k, v); err != nil {
+			return err
+		}
+	}
+	if err := check("body", api.Body); err != nil {
+		return err
+	}
+	return validateBody(api, data)
+}
+
+// validateBody body 渲染后须为合法 JSON；变量与密钥应通过 json 函数插入
+func validateBody(api *model.APIInfo, data templateData) error {
+	if api.Body == "" {
+		return nil
+	}
+	if api.Method != "POST/JSON" {
+		return nil
+	}
+	body, err := previewTemplate("body", api.Body, data)
+	if err != nil {
+		return fmt.Errorf("invalid template in body: %w", err)
+	}
+	if !json.Valid([]byte(body)) {
+		return fmt.Errorf("body must render to valid JSON; insert values with the json function, e.g. {{json .Date}}")
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package processor
+
+import (
+	"api-fetch/internal/api_fetch/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequireSecretRef(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		ok   bool
+	}{
+		{"secret reference", `{{secret "A"}}`, true},
+		{"secret reference with spaces", `  {{ secret "A" }}  `, true},
+		{"literal", "plain-credential", false},
+		{"empty", "", false},
+		{"trailing text", `{{secret "A"}}x`, false},
+		{"leading text", `x{{secret "A"}}`, false},
+		{"two references", `{{secret "A"}}{{secret "B"}}`, true},
+		{"literal in template", `{{"plain-credential"}}`, false},
+		{"template without secret", `{{.Date}}`, false},
+		{"invalid template", `{{secret "A"`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := requireSecretRef("auth.hmac.secret", tt.text)
+			if (err == nil) != tt.ok {
+				t.Fatalf("requireSecretRef(%q) error = %v, want ok = %v", tt.text, err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestValidateCredentialHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		ok      bool
+	}{
+		{"bearer secret", map[string]string{"Authorization": `Bearer {{secret "TOKEN"}}`}, true},
+		{"literal bearer", map[string]string{"Authorization": "Bearer abc"}, false},
+		{"lower-case name", map[string]string{"authorization": "Bearer abc"}, false},
+		{"literal cookie", map[string]string{"Cookie": "sid=abc"}, false},
+		{"other header", map[string]string{"X-Client": "news-fetcher"}, true},
+		{"empty value", map[string]string{"Authorization": ""}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateCredentialHeaders(tt.headers)
+			if (err == nil) != tt.ok {
+				t.Fatalf("validateCredentialHeaders(%v) error = %v, want ok = %v", tt.headers, err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestSecretSetMask(t *testing.T) {
+	const secret = `a"b/c d&e`
+	s := &secretSet{values: []string{secret}}
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"raw", "Bearer " + secret, "Bearer ***"},
+		{"query escaped", "https://example.com/x?k=a%22b%2Fc+d%26e", "https://example.com/x?k=***"},
+		{"path escaped", "https://example.com/a%22b%2Fc%20d&e/list", "https://example.com/***/list"},
+		{"json escaped", `{"token": "a\"b/c d\u0026e"}`, `{"token": "***"}`},
+		{"json escaped without html escaping", `{"token": "a\"b/c d&e"}`, `{"token": "***"}`},
+		{"no secret", "https://example.com/list", "https://example.com/list"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.mask(tt.text); got != tt.want {
+				t.Fatalf("mask(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+
+	var none *secretSet
+	if got := none.mask("Bearer " + secret); got != "Bearer "+secret {
+		t.Fatalf("nil mask changed text: %q", got)
+	}
+}
+
+func TestSecretLookupIsRecordedAndStubbed(t *testing.T) {
+	t.Setenv(secretEnvPrefix+"TEMPLATE_TEST", "s3cr3t")
+
+	data := newTemplateData(&model.APIInfo{}, time.Now(), 1, &pageRequest{})
+	got, err := renderTemplate("headers.Authorization", `Bearer {{secret "TEMPLATE_TEST"}}`, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "Bearer s3cr3t" {
+		t.Fatalf("rendered = %q", got)
+	}
+	if masked := data.secrets.mask(got); masked != "Bearer ***" {
+		t.Fatalf("masked = %q", masked)
+	}
+
+	got, err = previewTemplate("headers.Authorization", `Bearer {{secret "TEMPLATE_TEST"}}`, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "Bearer " {
+		t.Fatalf("preview = %q, want secret rendered as empty", got)
+	}
+}
+
+func TestNonceSize(t *testing.T) {
+	data := newTemplateData(&model.APIInfo{}, time.Now(), 1, &pageRequest{})
+	tests := []struct {
+		text    string
+		wantLen int // 0 表示应报错
+	}{
+		{"{{nonce}}", 32},
+		{"{{nonce 8}}", 16},
+		{"{{nonce 64}}", 128},
+		{"{{nonce 65}}", 0},
+		{"{{nonce 2000000000}}", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, err := renderTemplate("url", tt.text, data)
+			if tt.wantLen == 0 {
+				if err == nil {
+					t.Fatalf("render %s: want error, got %d chars", tt.text, len(got))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != tt.wantLen {
+				t.Fatalf("render %s: len = %d, want %d", tt.text, len(got), tt.wantLen)
+			}
+		})
+	}
+}
+
+func TestParseOffset(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+		err  bool
+	}{
+		{"-1d", -24 * time.Hour, false},
+		{"+2h30m", 2*time.Hour + 30*time.Minute, false},
+		{"1w", 7 * 24 * time.Hour, false},
+		{" 45s ", 45 * time.Second, false},
+		{"", 0, true},
+		{"-", 0, true},
+		{"d", 0, true},
+		{"1", 0, true},
+		{"1x", 0, true},
+		{"1d2", 0, true},
+		{"1.5h", 0, true},
+		{"--1d", 0, true},
+		{"99999999999999999999d", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseOffset(tt.in)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("parseOffset(%q) = %v, want error", tt.in, got)
+				}
+				if !strings.Contains(err.Error(), "offset") {
+					t.Fatalf("parseOffset(%q) error = %v, want an offset error", tt.in, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseOffset(%q) error = %v", tt.in, err)
+			}
+			if got != tt.want {
+				t.Fatalf("parseOffset(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,276 @@
+package processor
+
+import (
+	"api-fetch/internal/api_fetch/model"
+	"bytes"
+	"context"
+	"fmt"
+	"text/template"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.uber.org/zap"
+)
+
+// 规则未配置时的默认字段名
+const (
+	defaultIDTarget    = "articleID"
+	defaultURLTarget   = "origin_url"
+	defaultOutputField = "articles"
+)
+
+// processorKey 处理函数的注册键：source_category_infotype
+func processorKey(source, category, infoType string) string {
+	return fmt.Sprintf("%s_%s_%s", source, category, infoType)
+}
+
+// compiledRule 预编译后的转换规则
+type compiledRule struct {
+	rule        model.TransformRule
+	urlTemplate *template.Template
+}
+
+// compileRule 校验规则并预编译 URL 模板
+func compileRule(rule model.TransformRule) (*compiledRule, error) {
+	if rule.Source == "" || rule.Category == "" || rule.InfoType == "" {
+		return nil, fmt.Errorf("source, category and info_type are required")
+	}
+	for _, list := range rule.Lists {
+		if _, err := parsePath(list.Path); err != nil {
+			return nil, fmt.Errorf("lists: %w", err)
+		}
+	}
+	if err := validateRequiredOps(rule.Filters); err != nil {
+		return nil, fmt.Errorf("filters: %w", err)
+	}
+	for _, f := range rule.Fields {
+		if f.Target == "" || len(f.From) == 0 {
+			return nil, fmt.Errorf("fields: target and from are required")
+		}
+		for _, path := range f.From {
+			if _, err := parsePath(path); err != nil {
+				return nil, fmt.Errorf("fields %s: %w", f.Target, err)
+			}
+		}
+	}
+
+	cr := &compiledRule{rule: rule}
+	if rule.URLTemplate != "" {
+		tmpl, err := template.New("url").Option("missingkey=error").Parse(rule.URLTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("url_template: %w", err)
+		}
+		cr.urlTemplate = tmpl
+	}
+	return cr, nil
+}
+
+// ReloadRules 从 transform_rules 集合加载启用的规则（声明式或脚本）并注册为处理函数
+// 返回规则对应的处理配置，供调度器与内置配置合并
+func (dp *DataProcessor) ReloadRules(ctx context.Context) ([]DataProcessorConfig, error) {
+	ruleProcessors, scripts, configs, err := dp.loadRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	dp.mu.Lock()
+	dp.ruleProcessors = ruleProcessors
+	dp.scripts = scripts
+	dp.mu.Unlock()
+
+	return configs, nil
+}
+
+// WithLatestRules 返回使用 transform_rules 中最新规则的副本，不替换当前生效的规则，供试运行验证规则
+func (dp *DataProcessor) WithLatestRules(ctx context.Context) (*DataProcessor, error) {
+	ruleProcessors, scripts, _, err := dp.loadRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &DataProcessor{
+		Log:            dp.Log,
+		Stores:         dp.Stores,
+		Anomaly:        dp.Anomaly,
+		processors:     dp.processors,
+		ruleProcessors: ruleProcessors,
+		scripts:        scripts,
+	}, nil
+}
+
+// loadRules 读取并编译启用的规则，编译失败的规则记日志后跳过
+func (dp *DataProcessor) loadRules(ctx context.Context) (map[string]DataProcessorFunc, map[string]*compiledScript, []DataProcessorConfig, error) {
+	cur, err := dp.Stores.TransformRules.Find(ctx, bson.M{"enabled": true})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer cur.Close(ctx)
+
+	ruleProcessors := make(map[string]DataProcessorFunc)
+	scripts := make(map[string]*compiledScript)
+	var configs []DataProcessorConfig
+	for cur.Next(ctx) {
+		var rule model.TransformRule
+		if err := cur.Decode(&rule); err != nil {
+			dp.Log.Error("Failed to decode transform rule", zap.Error(err))
+			continue
+		}
+		key := processorKey(rule.Source, rule.Category, rule.InfoType)
+		if rule.Script != nil {
+			cs, err := dp.loadScript(key, rule.Script)
+			if err != nil {
+				dp.Log.Error("Invalid transform script, skipped",
+					zap.String("ruleId", rule.ID),
+					zap.String("processorKey", key),
+					zap.Error(err),
+				)
+				continue
+			}
+			scripts[key] = cs
+			ruleProcessors[key] = dp.scriptProcessor(key, cs)
+		} else {
+			cr, err := compileRule(rule)
+			if err != nil {
+				dp.Log.Error("Invalid transform rule, skipped",
+					zap.String("ruleId", rule.ID),
+					zap.String("source", rule.Source),
+					zap.Error(err),
+				)
+				continue
+			}
+			ruleProcessors[key] = dp.ruleProcessor(cr)
+		}
+
+		configs = append(configs, DataProcessorConfig{
+			Source:      rule.Source,
+			Category:    rule.Category,
+			InfoType:    rule.InfoType,
+			Enabled:     true,
+			ItemsField:  rule.OutputField,
+			DedupFields: rule.DedupFields,
+		})
+	}
+	if err := cur.Err(); err != nil {
+		return nil, nil, nil, err
+	}
+	return ruleProcessors, scripts, configs, nil
+}
+
+// ruleProcessor 将规则包装为 DataProcessorFunc
+func (dp *DataProcessor) ruleProcessor(cr *compiledRule) DataProcessorFunc {
+	return func(ctx context.Context, doc *model.CrawlResult) (*model.ProcessedData, error) {
+		if doc.Data == nil {
+			return nil, fmt.Errorf("empty data")
+		}
+
+		lists := cr.rule.Lists
+		if len(lists) == 0 {
+			lists = []model.ListRule{{Path: "items"}}
+		}
+
+		var allResults []interface{}
+		for _, list := range lists {
+			raw, ok := lookupPath(doc.Data, list.Path)
+			if !ok || raw == nil {
+				continue
+			}
+			arr := convertToSlice(raw, dp.Log)
+			if arr == nil {
+				dp.Log.Warn("failed to convert to slice", zap.String("path", list.Path))
+				continue
+			}
+			for i, item := range arr {
+				if transformed, ok := cr.apply(item, list.Label); ok {
+					allResults = append(allResults, transformed)
+				} else {
+					dp.Log.Debug("item filtered out",
+						zap.String("path", list.Path),
+						zap.Int("index", i))
+				}
+			}
+		}
+
+		outputField := cr.rule.OutputField
+		if outputField == "" {
+			outputField = defaultOutputField
+		}
+
+		return &model.ProcessedData{
+			Source:      doc.Source,
+			Category:    doc.Category,
+			InfoType:    doc.InfoType,
+			Date:        doc.Date,
+			ProcessedAt: time.Now(),
+			Data:        map[string]interface{}{outputField: allResults},
+			RawDocID:    doc.ID.Hex(),
+		}, nil
+	}
+}
+
+// apply 按规则转换单个条目；被过滤或缺少必填字段时返回 false
+func (cr *compiledRule) apply(item interface{}, label string) (map[string]interface{}, bool) {
+	m, ok := asMap(item)
+	if !ok {
+		return nil, false
+	}
+
+	for _, check := range evalRequired(m, cr.rule.Filters) {
+		if !check.Passed {
+			return nil, false
+		}
+	}
+
+	result := make(map[string]interface{})
+
+	if len(cr.rule.IDFields) > 0 {
+		id, ok := firstNonEmpty(m, cr.rule.IDFields)
+		if !ok {
+			return nil, false
+		}
+		target := cr.rule.IDTarget
+		if target == "" {
+			target = defaultIDTarget
+		}
+		result[target] = fmt.Sprintf("%v", id)
+	}
+
+	for _, f := range cr.rule.Fields {
+		if v, ok := firstNonEmpty(m, f.From); ok {
+			result[f.Target] = v
+		} else if f.Required {
+			return nil, false
+		} else if f.Default != nil {
+			result[f.Target] = f.Default
+		}
+	}
+
+	if cr.rule.LabelField != "" && label != "" {
+		result[cr.rule.LabelField] = label
+	}
+	for k, v := range cr.rule.Constants {
+		result[k] = v
+	}
+
+	if cr.urlTemplate != nil {
+		var buf bytes.Buffer
+		if err := cr.urlTemplate.Execute(&buf, result); err != nil {
+			return nil, false
+		}
+		target := cr.rule.URLTarget
+		if target == "" {
+			target = defaultURLTarget
+		}
+		result[target] = buf.String()
+	}
+
+	return result, true
+}
+
+// firstNonEmpty 按顺序返回第一个存在且非空的字段值
+func firstNonEmpty(m map[string]interface{}, paths []string) (interface{}, bool) {
+	for _, path := range paths {
+		if v, ok := lookupPath(m, path); ok && !isEmpty(v) {
+			return v, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,96 @@
+package processor
+
+import (
+	"api-fetch/internal/api_fetch/model"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// 支持的请求方式
+var supportedMethods = map[string]struct{}{
+	"GET":       {},
+	"POST/JSON": {},
+	"POST/FORM": {},
+}
+
+// ValidateAPIInfo 校验 API 配置能否被 Processor 正确抓取，Method 会被规范为大写
+func ValidateAPIInfo(api *model.APIInfo) error {
+	api.Method = strings.ToUpper(strings.TrimSpace(api.Method))
+	if _, ok := supportedMethods[api.Method]; !ok {
+		return fmt.Errorf("method must be one of GET, POST/JSON, POST/FORM, got %q", api.Method)
+	}
+
+	if err := validateTemplates(api); err != nil {
+		return err
+	}
+	if err := validateCredentialHeaders(api.Headers); err != nil {
+		return err
+	}
+	// 模板地址按当前时间渲染后再检查
+	rawURL, err := previewTemplate("url", api.URL, newTemplateData(api, time.Now(), 1, &pageRequest{}))
+	if err != nil {
+		return fmt.Errorf("url: %w", err)
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid url %q: must be an absolute http(s) url", rawURL)
+	}
+
+	if api.Source == "" || api.Category == "" || api.InfoType == "" {
+		return fmt.Errorf("source, category and info_type are required")
+	}
+
+	if api.DataField != "" && api.UseFullResponse {
+		return fmt.Errorf("data_field and use_full_response are mutually exclusive")
+	}
+	if api.DataField != "" {
+		if _, err := parsePath(api.DataField); err != nil {
+			return fmt.Errorf("data_field: %w", err)
+		}
+	}
+	if err := validateRequiredOps(api.Required); err != nil {
+		return err
+	}
+
+	if err := validateResponseFormat(api.ResponseFormat); err != nil {
+		return err
+	}
+	if responseFormat(api) == FormatHTML {
+		if err := validateHTMLExtract(api.HTML); err != nil {
+			return err
+		}
+	}
+	if err := validatePagination(api.Pagination); err != nil {
+		return err
+	}
+	if err := validateRetryPolicy(api.Retry); err != nil {
+		return err
+	}
+	if err := validateAuth(api.Auth); err != nil {
+		return err
+	}
+	return validateCharset(api.Charset)
+}
+
+// validateCredentialHeaders Authorization、Cookie 等请求头须通过 secret 引用凭据，如 "Bearer {{secret \"TOKEN\"}}"
+func validateCredentialHeaders(headers map[string]string) error {
+	for name, value := range headers {
+		if value == "" || !slices.ContainsFunc(redactedHeaders, func(h string) bool { return strings.EqualFold(h, name) }) {
+			continue
+		}
+		refs, _, err := secretRefs("headers."+name, value)
+		if err != nil {
+			return fmt.Errorf("headers.%s: %w", name, err)
+		}
+		if refs == 0 {
+			return fmt.Errorf(`headers.%s must take its credential from a {{secret "NAME"}} reference, literal credentials are not accepted`, name)
+		}
+	}
+	return nil
+}
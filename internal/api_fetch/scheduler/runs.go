@@ -0,0 +1,286 @@
+package scheduler
+
+import (
+	"api-fetch/internal/api_fetch/model"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+// 运行类型
+const (
+	RunKindFetch   = "fetch"   // API 抓取
+	RunKindProcess = "process" // 数据后处理
+)
+
+// 触发方式
+const (
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+)
+
+// 运行状态
+const (
+	RunRunning   = "running"
+	RunSucceeded = "succeeded"
+	RunPartial   = "partial" // 部分成功
+	RunFailed    = "failed"
+)
+
+// persistRunTimeout 写入 fetch_runs 的超时
+const persistRunTimeout = 5 * time.Second
+
+// RunQuery 运行记录的查询条件，空字段不过滤
+type RunQuery struct {
+	Kind    string
+	Trigger string
+	Status  string
+}
+
+// runRegistry 运行记录：进行中的运行保存在内存中累计结果，每次变化都异步写入 fetch_runs
+type runRegistry struct {
+	log  *zap.Logger
+	coll *mongo.Collection
+
+	mu      sync.Mutex
+	active  map[string]*model.FetchRun
+	pending map[string]model.FetchRun // 待写库的最新快照，同一运行只保留最后一份
+	writing bool                      // 写库协程是否在运行；同一时间只有一个，库中状态不会被较早的快照覆盖
+	writeWg sync.WaitGroup
+}
+
+func newRunRegistry(log *zap.Logger, coll *mongo.Collection) *runRegistry {
+	return &runRegistry{
+		log:     log,
+		coll:    coll,
+		active:  make(map[string]*model.FetchRun),
+		pending: make(map[string]model.FetchRun),
+	}
+}
+
+// start 登记一次运行，total 为待处理的单元数（API 数或文档数），未知时传 0
+func (r *runRegistry) start(kind, trigger string, filter map[string]string, total int) string {
+	run := &model.FetchRun{
+		ID:        primitive.NewObjectID().Hex(),
+		Kind:      kind,
+		Trigger:   trigger,
+		Filter:    filter,
+		Status:    RunRunning,
+		Total:     total,
+		StartedAt: time.Now().UTC(),
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.active[run.ID] = run
+	r.persistLocked(run)
+	return run.ID
+}
+
+// report 记录一个单元的结果，全部完成后结束运行
+func (r *runRegistry) report(id string, success bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	run, ok := r.active[id]
+	if !ok {
+		return
+	}
+	if success {
+		run.Succeeded++
+	} else {
+		run.Failed++
+	}
+	if run.Succeeded+run.Failed+run.Skipped >= run.Total {
+		r.finishLocked(run, "")
+	}
+	r.persistLocked(run)
+}
+
+// skip 记录一个被跳过的单元，全部完成后结束运行
+func (r *runRegistry) skip(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	run, ok := r.active[id]
+	if !ok {
+		return
+	}
+	run.Skipped++
+	if run.Succeeded+run.Failed+run.Skipped >= run.Total {
+		r.finishLocked(run, "")
+	}
+	r.persistLocked(run)
+}
+
+// addAnomalies 累计运行中条目数异常的次数
+func (r *runRegistry) addAnomalies(id string, n int) {
+	if n <= 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	run, ok := r.active[id]
+	if !ok {
+		return
+	}
+	run.Anomalies += n
+	r.persistLocked(run)
+}
+
+// finish 直接结束运行；errMsg 非空表示整体失败
+func (r *runRegistry) finish(id string, succeeded, failed int, errMsg string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	run, ok := r.active[id]
+	if !ok {
+		return
+	}
+	run.Succeeded, run.Failed = succeeded, failed
+	run.Total = succeeded + failed
+	r.finishLocked(run, errMsg)
+	r.persistLocked(run)
+}
+
+func (r *runRegistry) finishLocked(run *model.FetchRun, errMsg string) {
+	now := time.Now().UTC()
+	run.FinishedAt = &now
+	run.Error = errMsg
+	switch {
+	case errMsg != "" || (run.Failed > 0 && run.Succeeded == 0):
+		run.Status = RunFailed
+	case run.Failed > 0:
+		run.Status = RunPartial
+	default:
+		run.Status = RunSucceeded
+	}
+	delete(r.active, run.ID)
+}
+
+// persistLocked 登记运行快照待写库，调用方须持有 mu；写库在锁外进行，慢库不会阻塞上报结果的抓取协程
+func (r *runRegistry) persistLocked(run *model.FetchRun) {
+	if r.coll == nil {
+		return
+	}
+	r.pending[run.ID] = *run
+	if !r.writing {
+		r.writing = true
+		r.writeWg.Add(1)
+		go r.flush()
+	}
+}
+
+// flush 依次写入待写库的快照，直到没有新的快照；失败只记日志
+func (r *runRegistry) flush() {
+	defer r.writeWg.Done()
+	for {
+		r.mu.Lock()
+		if len(r.pending) == 0 {
+			r.writing = false
+			r.mu.Unlock()
+			return
+		}
+		batch := r.pending
+		r.pending = make(map[string]model.FetchRun)
+		r.mu.Unlock()
+
+		for _, run := range batch {
+			ctx, cancel := context.WithTimeout(context.Background(), persistRunTimeout)
+			_, err := r.coll.ReplaceOne(ctx, bson.M{"_id": run.ID}, run, options.Replace().SetUpsert(true))
+			cancel()
+			if err != nil {
+				r.log.Warn("Failed to persist fetch run",
+					zap.String("runId", run.ID),
+					zap.String("status", run.Status),
+					zap.Error(err),
+				)
+			}
+		}
+	}
+}
+
+// wait 等待已登记的快照写入完成，服务停止前调用
+func (r *runRegistry) wait() {
+	r.writeWg.Wait()
+}
+
+// markInterrupted 将上次进程退出时仍在运行的记录标记为失败
+func (r *runRegistry) markInterrupted(ctx context.Context) {
+	if r.coll == nil {
+		return
+	}
+	res, err := r.coll.UpdateMany(ctx,
+		bson.M{"status": RunRunning},
+		bson.M{"$set": bson.M{
+			"status":      RunFailed,
+			"error":       "interrupted by restart",
+			"finished_at": time.Now().UTC(),
+		}},
+	)
+	if err != nil {
+		r.log.Warn("Failed to mark interrupted fetch runs", zap.Error(err))
+		return
+	}
+	if res.ModifiedCount > 0 {
+		r.log.Info("Marked interrupted fetch runs as failed", zap.Int64("runs", res.ModifiedCount))
+	}
+}
+
+// get 查询运行记录，进行中的取内存中的最新状态；不存在时返回 nil
+func (r *runRegistry) get(ctx context.Context, id string) (*model.FetchRun, error) {
+	r.mu.Lock()
+	if run, ok := r.active[id]; ok {
+		cp := *run
+		r.mu.Unlock()
+		return &cp, nil
+	}
+	// 已结束但尚未写库
+	if run, ok := r.pending[id]; ok {
+		r.mu.Unlock()
+		return &run, nil
+	}
+	r.mu.Unlock()
+
+	if r.coll == nil {
+		return nil, nil
+	}
+	var run model.FetchRun
+	err := r.coll.FindOne(ctx, bson.M{"_id": id}).Decode(&run)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// list 按开始时间倒序返回最近的运行记录
+func (r *runRegistry) list(ctx context.Context, q RunQuery, limit int) ([]model.FetchRun, error) {
+	filter := bson.M{}
+	if q.Kind != "" {
+		filter["kind"] = q.Kind
+	}
+	if q.Trigger != "" {
+		filter["trigger"] = q.Trigger
+	}
+	if q.Status != "" {
+		filter["status"] = q.Status
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "started_at", Value: -1}}).SetLimit(int64(limit))
+	cur, err := r.coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	out := []model.FetchRun{}
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
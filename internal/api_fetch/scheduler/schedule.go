@@ -0,0 +1,122 @@
+package scheduler
+
+import (
+	"api-fetch/internal/api_fetch/model"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// defaultTimezone 未配置时区时使用的默认时区
+const defaultTimezone = "Asia/Shanghai"
+
+// cronParser 标准 5 段 cron 表达式解析器，支持 @daily、@every 等描述符
+var cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// Schedule 计算某个 API 的下一次执行时间
+type Schedule interface {
+	// Next 返回严格晚于 t 的下一次执行时间（UTC）
+	Next(t time.Time) time.Time
+	// String 返回计划的文本描述，用于日志和判断配置是否变化
+	String() string
+}
+
+// ParseSchedule 解析 API 的抓取计划；为空时返回默认的 3 小时锚点计划
+func ParseSchedule(cfg *model.Schedule) (Schedule, error) {
+	tz := defaultTimezone
+	if cfg != nil && cfg.Timezone != "" {
+		tz = cfg.Timezone
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", tz, err)
+	}
+
+	if cfg == nil || (cfg.Cron == "" && cfg.Interval == "") {
+		return anchorSchedule{loc: loc}, nil
+	}
+	if cfg.Cron != "" && cfg.Interval != "" {
+		return nil, fmt.Errorf("cron and interval are mutually exclusive")
+	}
+
+	if cfg.Interval != "" {
+		every, err := time.ParseDuration(cfg.Interval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid interval %q: %w", cfg.Interval, err)
+		}
+		if every < time.Minute {
+			return nil, fmt.Errorf("interval %q is shorter than 1m", cfg.Interval)
+		}
+		return intervalSchedule{every: every, loc: loc}, nil
+	}
+
+	if strings.HasPrefix(cfg.Cron, "CRON_TZ=") || strings.HasPrefix(cfg.Cron, "TZ=") {
+		return nil, fmt.Errorf("use the timezone field instead of %q", cfg.Cron)
+	}
+	spec, err := cronParser.Parse(cfg.Cron)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron %q: %w", cfg.Cron, err)
+	}
+	return cronSchedule{spec: spec, expr: cfg.Cron, loc: loc}, nil
+}
+
+// anchorSchedule 默认计划：每天 0, 3, 6, ... 21 点
+type anchorSchedule struct {
+	loc *time.Location
+}
+
+func (a anchorSchedule) Next(t time.Time) time.Time {
+	// next4x 在恰好处于锚点时返回当前时间，这里需要严格晚于 t
+	return next4x(t.Add(time.Nanosecond), a.loc)
+}
+
+func (a anchorSchedule) String() string {
+	return "anchors:3h@" + a.loc.String()
+}
+
+// intervalSchedule 固定间隔计划，执行时间按当地零点对齐（如 5m -> 每个 5 分钟整点）
+type intervalSchedule struct {
+	every time.Duration
+	loc   *time.Location
+}
+
+func (i intervalSchedule) Next(t time.Time) time.Time {
+	// 能整除一天的间隔按当地零点对齐，否则直接顺延
+	if (24*time.Hour)%i.every != 0 {
+		return t.Add(i.every).UTC()
+	}
+	local := t.In(i.loc)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, i.loc)
+	n := local.Sub(midnight)/i.every + 1
+	return midnight.Add(n * i.every).UTC()
+}
+
+func (i intervalSchedule) String() string {
+	return "every:" + i.every.String() + "@" + i.loc.String()
+}
+
+// cronSchedule cron 表达式计划，在指定时区内计算
+type cronSchedule struct {
+	spec cron.Schedule
+	expr string
+	loc  *time.Location
+}
+
+func (c cronSchedule) Next(t time.Time) time.Time {
+	return c.spec.Next(t.In(c.loc)).UTC()
+}
+
+func (c cronSchedule) String() string {
+	return "cron:" + c.expr + "@" + c.loc.String()
+}
+
+// MissedRuns 统计 (since, now] 之间计划执行的次数，最多数到 limit 次
+func MissedRuns(s Schedule, since, now time.Time, limit int) int {
+	n := 0
+	for t := s.Next(since); !t.After(now) && n < limit; t = s.Next(t) {
+		n++
+	}
+	return n
+}
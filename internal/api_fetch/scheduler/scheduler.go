@@ -1,11 +1,14 @@
 package scheduler
 
 import (
+	"api-fetch/internal/api_fetch/alert"
 	"api-fetch/internal/api_fetch/helper"
 	"api-fetch/internal/api_fetch/model"
 	"api-fetch/internal/api_fetch/processor"
 	"context"
+	"fmt"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
@@ -21,19 +24,50 @@ type Scheduler struct {
 	processor     *processor.Processor     // API数据获取处理器
 	dataProcessor *processor.DataProcessor // 数据后处理器
 	retryWg       sync.WaitGroup
+	runs          *runRegistry // 抓取/后处理运行记录
+	alerts        *alert.Manager
+
+	// 每个 API 连续失败的运行次数，成功后清零
+	failMu   sync.Mutex
+	failures map[string]int
+
+	// 正在抓取（含异步重试）的 API，上一次未结束时跳过，避免同一 API 的运行重叠
+	flightMu sync.Mutex
+	inFlight map[string]struct{}
+
+	// 手动触发的任务使用调度器的 ctx，随服务一起停止
+	mu      sync.Mutex
+	baseCtx context.Context
+	jobsWg  sync.WaitGroup
+
+	// 数据处理调度协程会向 jobsWg 登记后处理任务，须在 jobsWg.Wait 之前退出
+	dpWg sync.WaitGroup
 }
 
-// NewScheduler 创建新的调度器
-func NewScheduler(log *zap.Logger, stores *helper.Stores, httpClient *http.Client) *Scheduler {
+// NewScheduler 创建新的调度器，alerts 可为空，anomaly 为条目数异常检测配置，fetch 为抓取并发与限速配置
+func NewScheduler(log *zap.Logger, stores *helper.Stores, httpClient *http.Client, alerts *alert.Manager, anomaly processor.AnomalyConfig, fetch processor.FetchConfig) *Scheduler {
 	scheduler := &Scheduler{
 		Log:        log,
 		Stores:     stores,
 		HTTPClient: httpClient,
+		runs:       newRunRegistry(log, stores.FetchRuns),
+		alerts:     alerts,
+		failures:   make(map[string]int),
+		inFlight:   make(map[string]struct{}),
 	}
 	// 创建API处理器实例
 	scheduler.processor = processor.NewProcessor(log, stores, httpClient)
+	scheduler.processor.Alerts = alerts
+	scheduler.processor.Anomaly = anomaly
+	scheduler.processor.Limiter = processor.NewFetchLimiter(fetch)
+	scheduler.processor.Breakers = processor.NewBreakers(fetch.Breaker, alerts)
+	scheduler.processor.OnItemAnomaly = func(a *model.ItemAnomaly) {
+		scheduler.runs.addAnomalies(a.RunID, 1)
+	}
 	// 创建数据后处理器实例
 	scheduler.dataProcessor = processor.NewDataProcessor(log, stores)
+	scheduler.dataProcessor.Alerts = alerts
+	scheduler.dataProcessor.Anomaly = anomaly
 	return scheduler
 }
 
@@ -66,52 +100,85 @@ func every5minutes(now time.Time) time.Time {
 	return rounded
 }
 
-// Run 启动调度器主循环
-func (s *Scheduler) Run(ctx context.Context) {
+// Run 启动调度器主循环，阻塞直到 ctx 取消且所有重试协程退出
+func (s *Scheduler) Run(ctx context.Context) error {
 	s.Log.Info("Scheduler starting...")
 
+	s.mu.Lock()
+	s.baseCtx = ctx
+	s.mu.Unlock()
+
+	s.runs.markInterrupted(ctx)
+
 	// 立即执行一次（可选）
 	s.runOnce(ctx)
 
 	// 启动数据处理调度器
-	go s.runDataProcessorScheduler(ctx)
+	s.dpWg.Add(1)
+	go func() {
+		defer s.dpWg.Done()
+		s.runDataProcessorScheduler(ctx)
+	}()
 
-	// 主循环：API抓取调度
-	shanghai, _ := time.LoadLocation("Asia/Shanghai")
+	// 主循环：每个 API 按各自的计划抓取，API 列表每 5 分钟从库中刷新一次
+	wheel := newTimingWheel(s.Log)
+	nextReload := s.reloadWheel(ctx, wheel, time.Now())
 	for {
+		next := wheel.nextDue()
+		if next.IsZero() || nextReload.Before(next) {
+			next = nextReload
+		}
+		sleep := time.Until(next)
+		if sleep < 0 {
+			sleep = 0
+		}
+
+		s.Log.Debug("API Scheduler sleeping until next execution",
+			zap.Time("nextExecution", next),
+			zap.Duration("sleepDuration", sleep),
+		)
+
+		timer := time.NewTimer(sleep)
 		select {
 		case <-ctx.Done():
+			timer.Stop()
 			s.Log.Info("Scheduler stopping, waiting for retry goroutines to complete...")
+			s.dpWg.Wait()
+			s.jobsWg.Wait()
 			s.retryWg.Wait()
+			s.runs.wait()
 			s.Log.Info("Scheduler stopped")
-			return
-		default:
-			next := next4x(time.Now(), shanghai)
-			sleep := time.Until(next)
-			if sleep < 0 {
-				sleep = 0
+			return nil
+		case <-timer.C:
+			now := time.Now()
+			if !now.Before(nextReload) {
+				nextReload = s.reloadWheel(ctx, wheel, now)
 			}
-
-			s.Log.Info("API Scheduler sleeping until next execution",
-				zap.Time("nextExecution", next),
-				zap.Duration("sleepDuration", sleep),
-			)
-
-			timer := time.NewTimer(sleep)
-			select {
-			case <-ctx.Done():
-				timer.Stop()
-				s.Log.Info("Scheduler stopping, waiting for retry goroutines to complete...")
-				s.retryWg.Wait()
-				s.Log.Info("Scheduler stopped")
-				return
-			case <-timer.C:
-				s.runOnce(ctx)
+			if due := wheel.popDue(now); len(due) > 0 {
+				runID := s.runs.start(RunKindFetch, TriggerSchedule, nil, len(due))
+				s.runAPIs(ctx, due, now, runID)
 			}
 		}
 	}
 }
 
+// reloadWheel 从库中读取启用的 API 刷新时间轮，返回下一次刷新时间
+func (s *Scheduler) reloadWheel(ctx context.Context, wheel *timingWheel, now time.Time) time.Time {
+	apis, err := s.loadEnabledAPIs(ctx)
+	if err != nil {
+		// 读取失败时保留现有时间轮，等下一次刷新
+		s.Log.Error("Failed to reload API schedules", zap.Error(err))
+	} else {
+		wheel.sync(apis, now)
+	}
+
+	next := every5minutes(now)
+	if !next.After(now) {
+		next = next.Add(5 * time.Minute)
+	}
+	return next
+}
+
 // runDataProcessorScheduler 数据处理调度器
 func (s *Scheduler) runDataProcessorScheduler(ctx context.Context) {
 	shanghai, _ := time.LoadLocation("Asia/Shanghai")
@@ -146,72 +213,215 @@ func (s *Scheduler) runDataProcessorScheduler(ctx context.Context) {
 	}
 }
 
-// runOnce 执行一次完整的API数据抓取流程
+// runOnce 对所有启用的 API 执行一次完整的数据抓取流程
 func (s *Scheduler) runOnce(ctx context.Context) {
-	now := time.Now()
-	s.Log.Info("Starting scheduled API fetch execution", zap.Time("executionTime", now))
-
-	// 1) 读取启用的 API 配置
-	cur, err := s.Stores.APIs.Find(ctx, bson.M{"enabled": true})
+	apis, err := s.loadEnabledAPIs(ctx)
 	if err != nil {
 		s.Log.Error("Failed to find enabled APIs", zap.Error(err))
 		return
 	}
+	runID := s.runs.start(RunKindFetch, TriggerSchedule, nil, len(apis))
+	if len(apis) == 0 {
+		s.runs.finish(runID, 0, 0, "")
+		return
+	}
+	s.runAPIs(ctx, apis, time.Now(), runID)
+}
+
+// loadEnabledAPIs 读取启用的 API 配置
+func (s *Scheduler) loadEnabledAPIs(ctx context.Context) ([]model.APIInfo, error) {
+	return s.loadAPIs(ctx, bson.M{"enabled": true})
+}
+
+// loadAPIs 按条件读取 API 配置
+func (s *Scheduler) loadAPIs(ctx context.Context, filter bson.M) ([]model.APIInfo, error) {
+	cur, err := s.Stores.APIs.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
 	defer func(cur *mongo.Cursor, ctx context.Context) {
 		if err := cur.Close(ctx); err != nil {
 			s.Log.Warn("Failed to close cursor", zap.Error(err))
 		}
 	}(cur, ctx)
 
-	// 2) 确保当天分表存在并创建索引
-	collName := helper.RawDataCollName(now)
-	helper.EnsureRawDataIndexes(ctx, s.Stores.DB, collName)
-	contentColl := s.Stores.DB.Collection(collName)
-
-	// 3) 处理每个 API
-	apiCount := 0
+	var apis []model.APIInfo
 	for cur.Next(ctx) {
 		var api model.APIInfo
 		if err := cur.Decode(&api); err != nil {
 			s.Log.Error("Failed to decode API config", zap.Error(err))
 			continue
 		}
+		apis = append(apis, api)
+	}
+	return apis, cur.Err()
+}
+
+// runAPIs 在后台抓取给定的 API 列表并写入 now 对应的当天分表，每个 API 的最终结果记入 runID，不等待抓取完成
+func (s *Scheduler) runAPIs(ctx context.Context, apis []model.APIInfo, now time.Time, runID string) {
+	s.Log.Info("Starting scheduled API fetch execution",
+		zap.Time("executionTime", now),
+		zap.Int("dueAPIs", len(apis)),
+	)
+
+	// 确保当天分表存在并创建索引
+	collName := helper.RawDataCollName(now)
+	helper.EnsureRawDataIndexes(ctx, s.Stores.DB, collName)
+	contentColl := s.Stores.DB.Collection(collName)
 
+	// 每个 API 一个协程，由处理器的并发槽位限制同时抓取的数量，慢接口不会阻塞其他 API 与调度主循环
+	for i := range apis {
+		api := apis[i]
+		key := wheelKey(&api)
+		if !s.startFlight(key) {
+			s.Log.Warn("Skipping API whose previous run is still in flight",
+				zap.String("source", api.Source),
+				zap.String("category", api.Category),
+				zap.String("runId", runID),
+			)
+			s.runs.skip(runID)
+			continue
+		}
 		s.Log.Info("Processing API",
 			zap.String("source", api.Source),
 			zap.String("category", api.Category),
 			zap.String("url", api.URL),
 		)
 
-		// 使用处理器进行数据抓取和保存（包含重试机制）
-		s.processor.ProcessAPIWithRetry(ctx, &api, contentColl, now, &s.retryWg)
-		apiCount++
+		s.jobsWg.Add(1)
+		go func() {
+			defer s.jobsWg.Done()
+			// 使用处理器进行数据抓取和保存（包含重试机制）
+			s.processor.ProcessAPIWithRetry(ctx, &api, contentColl, now, &s.retryWg, runID, func(err error) {
+				s.endFlight(key)
+				s.runs.report(runID, err == nil)
+				s.trackFailures(&api, err)
+			})
+		}()
 	}
 
-	s.Log.Info("Scheduled API fetch execution completed",
+	s.Log.Info("Scheduled API fetch dispatched",
 		zap.Time("executionTime", now),
-		zap.Int("processedAPIs", apiCount),
+		zap.Int("dispatchedAPIs", len(apis)),
 	)
 }
 
+// startFlight 登记 API 开始抓取，上一次仍在进行时返回 false
+func (s *Scheduler) startFlight(key string) bool {
+	s.flightMu.Lock()
+	defer s.flightMu.Unlock()
+	if _, ok := s.inFlight[key]; ok {
+		return false
+	}
+	s.inFlight[key] = struct{}{}
+	return true
+}
+
+// endFlight API 的抓取（含重试）全部结束
+func (s *Scheduler) endFlight(key string) {
+	s.flightMu.Lock()
+	delete(s.inFlight, key)
+	s.flightMu.Unlock()
+}
+
+// trackFailures 累计 API 连续失败的运行次数，达到阈值后告警（冷却期内不重复发送）；
+// 服务停止导致的失败与熔断跳过的运行不说明该 API 本身的状态（熔断另有 circuit_open 告警），不计入也不清零
+func (s *Scheduler) trackFailures(api *model.APIInfo, err error) {
+	switch processor.ErrorClass(err) {
+	case processor.ErrClassCanceled, processor.ErrClassCircuit:
+		return
+	}
+	key := wheelKey(api)
+	s.failMu.Lock()
+	if err == nil {
+		delete(s.failures, key)
+		s.failMu.Unlock()
+		return
+	}
+	s.failures[key]++
+	n := s.failures[key]
+	s.failMu.Unlock()
+
+	if n < s.alerts.ConsecutiveFailures() {
+		return
+	}
+	s.alerts.Notify(alert.Alert{
+		Event:    alert.EventConsecutiveFailures,
+		Source:   api.Source,
+		Category: api.Category,
+		InfoType: api.InfoType,
+		APIID:    api.ID,
+		Title:    fmt.Sprintf("%s/%s failed %d runs in a row", api.Source, api.Category, n),
+		Message:  "The source has not produced data for several consecutive runs.",
+		Fields:   map[string]string{"url": api.URL, "consecutive_failures": strconv.Itoa(n)},
+	})
+}
+
 // runDataProcessor 执行数据后处理
 func (s *Scheduler) runDataProcessor(ctx context.Context) {
+	if ctx.Err() != nil {
+		return
+	}
 	now := time.Now()
 	s.Log.Info("Starting scheduled data processing execution", zap.Time("executionTime", now))
 
+	// 每个启用的配置各自登记一次运行并在独立协程中处理当天的数据
+	day := now.Format("2006-01-02")
+	for _, cfg := range s.dataProcessorConfigs(ctx) {
+		if !cfg.Enabled {
+			continue
+		}
+		runID := s.runs.start(RunKindProcess, TriggerSchedule, map[string]string{"key": cfg.Key(), "date": day}, 0)
+		s.jobsWg.Add(1)
+		go func(cfg processor.DataProcessorConfig) {
+			defer s.jobsWg.Done()
+			s.processDate(ctx, cfg, now, runID)
+		}(cfg)
+	}
+
+	s.Log.Info("Scheduled data processing execution completed", zap.Time("executionTime", now))
+}
+
+// dataProcessorConfigs 内置处理配置与 transform_rules 中的规则合并后的配置
+func (s *Scheduler) dataProcessorConfigs(ctx context.Context) []processor.DataProcessorConfig {
 	// 配置数据处理器
 	configs := []processor.DataProcessorConfig{
 		{
-			Source:   "澎湃",
-			Category: "general",
-			InfoType: "daily",
-			Enabled:  true,
+			Source:      "澎湃",
+			Category:    "general",
+			InfoType:    "daily",
+			Enabled:     true,
+			DedupFields: []string{"articleID"},
 		},
 		// 可以在这里添加更多配置
 	}
 
-	// 运行数据处理器
-	s.dataProcessor.Run(ctx, configs)
+	// 合并 transform_rules 中的声明式规则，同名配置以规则为准
+	ruleConfigs, err := s.dataProcessor.ReloadRules(ctx)
+	if err != nil {
+		s.Log.Error("Failed to load transform rules", zap.Error(err))
+	}
+	index := make(map[string]int, len(configs))
+	for i, c := range configs {
+		index[c.Key()] = i
+	}
+	for _, c := range ruleConfigs {
+		if i, ok := index[c.Key()]; ok {
+			configs[i] = c
+			continue
+		}
+		configs = append(configs, c)
+	}
+	return configs
+}
 
-	s.Log.Info("Scheduled data processing execution completed", zap.Time("executionTime", now))
+// DryRun 试运行单个 API：抓取第一页并执行后处理，不写库；使用 transform_rules 中最新规则的副本，
+// 以便验证刚修改的规则，不影响正在生效的规则。resolveSecrets 为 false 时不解析 secret 引用，用于未入库的配置
+func (s *Scheduler) DryRun(ctx context.Context, api *model.APIInfo, resolveSecrets bool) *processor.DryRunResult {
+	dp, err := s.dataProcessor.WithLatestRules(ctx)
+	if err != nil {
+		s.Log.Warn("Failed to load transform rules for dry run, using active rules", zap.Error(err))
+		dp = s.dataProcessor
+	}
+	return s.processor.DryRun(ctx, api, dp, resolveSecrets)
 }
@@ -0,0 +1,165 @@
+package scheduler
+
+import (
+	"api-fetch/internal/api_fetch/model"
+	"api-fetch/internal/api_fetch/processor"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrNotRunning       = errors.New("scheduler is not running")
+	ErrNoMatchingAPIs   = errors.New("no matching apis")
+	ErrUnknownProcessor = errors.New("unknown processor key")
+)
+
+// FetchFilter 手动抓取的范围：指定 APIID 时只抓该 API（不论是否启用），
+// 否则抓取匹配 Source/Category 的启用 API，均为空时抓取全部启用 API
+type FetchFilter struct {
+	APIID    string `json:"api_id,omitempty"`
+	Source   string `json:"source,omitempty"`
+	Category string `json:"category,omitempty"`
+}
+
+func (f FetchFilter) query() bson.M {
+	if f.APIID != "" {
+		if oid, err := primitive.ObjectIDFromHex(f.APIID); err == nil {
+			return bson.M{"_id": bson.M{"$in": bson.A{f.APIID, oid}}}
+		}
+		return bson.M{"_id": f.APIID}
+	}
+	q := bson.M{"enabled": true}
+	if f.Source != "" {
+		q["source"] = f.Source
+	}
+	if f.Category != "" {
+		q["category"] = f.Category
+	}
+	return q
+}
+
+func (f FetchFilter) labels() map[string]string {
+	m := map[string]string{}
+	if f.APIID != "" {
+		m["api_id"] = f.APIID
+	}
+	if f.Source != "" {
+		m["source"] = f.Source
+	}
+	if f.Category != "" {
+		m["category"] = f.Category
+	}
+	return m
+}
+
+// jobContext 返回调度器运行时的 ctx，调度器未启动或已停止时返回错误
+func (s *Scheduler) jobContext() (context.Context, error) {
+	s.mu.Lock()
+	ctx := s.baseCtx
+	s.mu.Unlock()
+	if ctx == nil || ctx.Err() != nil {
+		return nil, ErrNotRunning
+	}
+	return ctx, nil
+}
+
+// TriggerFetch 立即在后台抓取匹配的 API，返回可轮询的运行 ID
+func (s *Scheduler) TriggerFetch(filter FetchFilter) (string, error) {
+	ctx, err := s.jobContext()
+	if err != nil {
+		return "", err
+	}
+
+	apis, err := s.loadAPIs(ctx, filter.query())
+	if err != nil {
+		return "", err
+	}
+	if len(apis) == 0 {
+		return "", ErrNoMatchingAPIs
+	}
+
+	runID := s.runs.start(RunKindFetch, TriggerManual, filter.labels(), len(apis))
+	s.Log.Info("Manual fetch triggered",
+		zap.String("runId", runID),
+		zap.Any("filter", filter),
+		zap.Int("apis", len(apis)),
+	)
+
+	s.jobsWg.Add(1)
+	go func() {
+		defer s.jobsWg.Done()
+		s.runAPIs(ctx, apis, time.Now(), runID)
+	}()
+	return runID, nil
+}
+
+// TriggerProcess 立即在后台对 date 当天的分表执行 key 对应的后处理，返回可轮询的运行 ID
+func (s *Scheduler) TriggerProcess(key string, date time.Time) (string, error) {
+	ctx, err := s.jobContext()
+	if err != nil {
+		return "", err
+	}
+
+	var cfg *processor.DataProcessorConfig
+	for _, c := range s.dataProcessorConfigs(ctx) {
+		if c.Key() == key {
+			cfg = &c
+			break
+		}
+	}
+	if cfg == nil {
+		return "", fmt.Errorf("%w: %s", ErrUnknownProcessor, key)
+	}
+
+	day := date.Format("2006-01-02")
+	runID := s.runs.start(RunKindProcess, TriggerManual, map[string]string{"key": key, "date": day}, 0)
+	s.Log.Info("Manual data processing triggered",
+		zap.String("runId", runID),
+		zap.String("processorKey", key),
+		zap.String("date", day),
+	)
+
+	s.jobsWg.Add(1)
+	go func() {
+		defer s.jobsWg.Done()
+		s.processDate(ctx, *cfg, date, runID)
+	}()
+	return runID, nil
+}
+
+// processDate 执行一次后处理并结束对应的运行记录
+func (s *Scheduler) processDate(ctx context.Context, cfg processor.DataProcessorConfig, date time.Time, runID string) {
+	stats, err := s.dataProcessor.ProcessDate(ctx, cfg, date, runID)
+	errMsg := ""
+	if err != nil {
+		errMsg = err.Error()
+	}
+	s.runs.addAnomalies(runID, stats.Anomalies)
+	s.runs.finish(runID, stats.Processed, stats.Failed, errMsg)
+}
+
+// GetRun 查询运行状态，不存在时返回 nil
+func (s *Scheduler) GetRun(ctx context.Context, id string) (*model.FetchRun, error) {
+	return s.runs.get(ctx, id)
+}
+
+// ListRuns 按开始时间倒序列出运行记录
+func (s *Scheduler) ListRuns(ctx context.Context, q RunQuery, limit int) ([]model.FetchRun, error) {
+	return s.runs.list(ctx, q, limit)
+}
+
+// Breakers 各 host（或 API）熔断器的当前状态
+func (s *Scheduler) Breakers() []processor.BreakerState {
+	return s.processor.Breakers.States()
+}
+
+// ResetBreaker 手动关闭熔断器，不存在时返回 false
+func (s *Scheduler) ResetBreaker(key string) bool {
+	return s.processor.Breakers.Reset(key)
+}
@@ -0,0 +1,115 @@
+package scheduler
+
+import (
+	"api-fetch/internal/api_fetch/metrics"
+	"api-fetch/internal/api_fetch/model"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// wheelEntry 时间轮中单个 API 的槽位
+type wheelEntry struct {
+	api   model.APIInfo
+	sched Schedule
+	next  time.Time
+}
+
+// timingWheel 按 API 维护各自的下一次执行时间
+// 只在调度器主循环中使用，不需要加锁
+type timingWheel struct {
+	log     *zap.Logger
+	entries map[string]*wheelEntry
+}
+
+func newTimingWheel(log *zap.Logger) *timingWheel {
+	return &timingWheel{
+		log:     log,
+		entries: make(map[string]*wheelEntry),
+	}
+}
+
+// sync 用最新的启用 API 列表刷新时间轮：
+// 新增的 API 从 now 开始计算下一次执行时间，计划变更的重新计算，已删除或禁用的移除
+func (w *timingWheel) sync(apis []model.APIInfo, now time.Time) {
+	seen := make(map[string]struct{}, len(apis))
+	for _, api := range apis {
+		key := wheelKey(&api)
+		seen[key] = struct{}{}
+
+		sched, err := ParseSchedule(api.Schedule)
+		if err != nil {
+			w.log.Error("Invalid API schedule, falling back to default anchors",
+				zap.String("source", api.Source),
+				zap.String("category", api.Category),
+				zap.Error(err),
+			)
+			sched, _ = ParseSchedule(nil)
+		}
+
+		entry, exists := w.entries[key]
+		if exists && entry.sched.String() == sched.String() {
+			entry.api = api // 其他配置（URL、参数等）直接更新
+			continue
+		}
+
+		w.entries[key] = &wheelEntry{
+			api:   api,
+			sched: sched,
+			next:  sched.Next(now),
+		}
+		w.log.Info("API scheduled",
+			zap.String("source", api.Source),
+			zap.String("category", api.Category),
+			zap.String("schedule", sched.String()),
+			zap.Time("nextExecution", w.entries[key].next),
+		)
+	}
+
+	for key := range w.entries {
+		if _, ok := seen[key]; !ok {
+			delete(w.entries, key)
+		}
+	}
+}
+
+// nextDue 返回最早的执行时间；时间轮为空时返回零值
+func (w *timingWheel) nextDue() time.Time {
+	var earliest time.Time
+	for _, e := range w.entries {
+		if earliest.IsZero() || e.next.Before(earliest) {
+			earliest = e.next
+		}
+	}
+	return earliest
+}
+
+// popDue 取出所有已到期的 API，并将其推进到下一次执行时间
+func (w *timingWheel) popDue(now time.Time) []model.APIInfo {
+	var due []*wheelEntry
+	for _, e := range w.entries {
+		if !e.next.After(now) {
+			due = append(due, e)
+		}
+	}
+	// 按计划时间排序，保证执行顺序稳定
+	sort.Slice(due, func(i, j int) bool { return due[i].next.Before(due[j].next) })
+
+	apis := make([]model.APIInfo, 0, len(due))
+	for _, e := range due {
+		apis = append(apis, e.api)
+		metrics.SchedulerLag(e.api.Source, e.api.Category, e.api.InfoType, now.Sub(e.next))
+		// 错过的执行点不补跑，直接跳到 now 之后
+		e.next = e.sched.Next(now)
+	}
+	return apis
+}
+
+// wheelKey 时间轮槽位的键，优先使用 API ID
+func wheelKey(api *model.APIInfo) string {
+	if api.ID != "" {
+		return api.ID
+	}
+	return api.Source + "_" + api.Category + "_" + api.InfoType + "_" + api.URL
+}